@@ -0,0 +1,403 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	registerParser("asciidoc", asciidocSegments)
+}
+
+var (
+	asciidocTitleRegex      = regexp.MustCompile(`^(={1,6}|#{1,6})[ \t]+(\S.*?)(?:[ \t]+=+)?$`)
+	asciidocBlockTitleRegex = regexp.MustCompile(`^\.[^\s.]`)
+	asciidocAttributeRegex  = regexp.MustCompile(`^:!?\w[\w-]*!?:(?:[ \t]|$)`)
+	asciidocBlockAttrRegex  = regexp.MustCompile(`^\[.*\]$`)
+	asciidocBlockMacroRegex = regexp.MustCompile(`^[A-Za-z][\w-]*::\S*\[.*\]$`)
+	asciidocDelimiterRegex  = regexp.MustCompile("^(?:-{4,}|\\.{4,}|\\+{4,}|/{4,}|={4,}|\\*{4,}|_{4,}|--|```.*|[|,:!]={3,})$")
+	asciidocListRegex       = regexp.MustCompile(`^[ \t]*(?:\*{1,5}|-|\.{1,5}|\d+\.|[a-zA-Z]\.|[ivxIVX]+\))[ \t]+(?:\[[ x*]\][ \t]+)?`)
+	asciidocTermRegex       = regexp.MustCompile(`^[ \t]*(\S.*?)(?::{2,4}|;;)(?:[ \t]+|$)`)
+	asciidocAdmonitionRegex = regexp.MustCompile(`^(?:NOTE|TIP|IMPORTANT|CAUTION|WARNING):[ \t]+`)
+	asciidocCellSpecRegex   = regexp.MustCompile(`(?:^|[ \t])((?:\d+\*)?(?:\d*(?:\.\d+)?\+)?[<^>]?(?:\.[<^>])?[adehlmsv]?)$`)
+
+	asciidocAttributeRef = regexp.MustCompile(`^\{[A-Za-z0-9_][\w-]*\}`)
+	asciidocAnchorRegex  = regexp.MustCompile(`^\[\[[^\]]*\]\]`)
+	asciidocRoleRegex    = regexp.MustCompile(`^\[[^\]\s]*\]#`)
+	asciidocXrefRegex    = regexp.MustCompile(`^<<([^,>]+)(?:,[ \t]*([^>]+))?>>`)
+	asciidocURLRegex     = regexp.MustCompile(`^(?:https?|ftps?|irc)://[^\s\[\]<>]*[^\s\[\]<>.,;:!?)'"]`)
+	asciidocMacroRegex   = regexp.MustCompile(`^([a-z][\w-]*):([^\s\[]*)\[([^\]]*)\]`)
+)
+
+// asciidocSkippedStyles mark blocks and paragraphs that aren't prose, like
+// [source] or [stem].
+var asciidocSkippedStyles = map[string]bool{
+	"source": true, "listing": true, "literal": true, "pass": true, "stem": true, "latexmath": true,
+	"asciimath": true, "comment": true, "plantuml": true, "ditaa": true, "graphviz": true, "mermaid": true,
+}
+
+// asciidocSegments extracts the prose of an AsciiDoc document: titles,
+// paragraphs, admonitions, list items and table cells. Listing, literal,
+// passthrough and comment blocks, attribute entries, includes and other
+// block macros are skipped.
+func asciidocSegments(doc *document) []segment {
+	p := &asciidocParser{doc: doc}
+	for i := range doc.lineStarts {
+		line := doc.lineSpan(i)
+		line.end = line.start + len(strings.TrimRight(doc.text[line.start:line.end], " \t"))
+		p.lines = append(p.lines, line)
+	}
+	p.parse(0, len(p.lines))
+	return p.segments
+}
+
+type asciidocParser struct {
+	doc      *document
+	lines    []span
+	segments []segment
+}
+
+func (p *asciidocParser) text(i int) string {
+	return p.doc.text[p.lines[i].start:p.lines[i].end]
+}
+
+// until returns the index of the first line from i on that is blank or
+// matches stop.
+func (p *asciidocParser) until(i, end int, stop func(text string) bool) int {
+	for i < end && p.text(i) != "" && !stop(p.text(i)) {
+		i++
+	}
+	return i
+}
+
+// style returns the block style of an attribute list like [source,go] or
+// [NOTE.role], in lower case.
+func asciidocStyle(text string) string {
+	style, _, _ := strings.Cut(text[1:len(text)-1], ",")
+	if i := strings.IndexAny(style, "#.%"); i != -1 {
+		style = style[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(style))
+}
+
+func (p *asciidocParser) parse(start, end int) {
+	style := ""
+	for i := start; i < end; {
+		text := p.text(i)
+		switch {
+		case text == "":
+			i++
+			continue
+
+		case asciidocDelimiterRegex.MatchString(text):
+			closing := text
+			if strings.HasPrefix(text, "```") {
+				closing = "```"
+			}
+			close := i + 1
+			for close < end && p.text(close) != closing {
+				close++
+			}
+			switch {
+			case asciidocSkippedStyles[style] || strings.ContainsAny(text[:1], "-.+/`,:") && text != "--":
+			case text[0] == '|' || text[0] == '!':
+				p.table(i+1, close, text[0])
+			default:
+				p.parse(i+1, close)
+			}
+			i = close + 1
+
+		case strings.HasPrefix(text, "//"):
+			i++
+			continue
+
+		case asciidocAttributeRegex.MatchString(text):
+			// Values can continue on the next line after " \"
+			for i++; i < end && strings.HasSuffix(p.text(i-1), " \\"); i++ {
+			}
+			continue
+
+		case asciidocBlockAttrRegex.MatchString(text):
+			if !strings.HasPrefix(text, "[[") {
+				style = asciidocStyle(text)
+			}
+			i++
+			continue
+
+		case asciidocBlockMacroRegex.MatchString(text):
+			i++
+
+		case asciidocTitleRegex.MatchString(text):
+			match := asciidocTitleRegex.FindStringSubmatchIndex(text)
+			start := p.lines[i].start
+			p.paragraph([]span{{start + match[4], start + match[5]}})
+			i++
+			// The header of the document follows its title up to a blank line
+			if text[:match[3]] == "=" {
+				i = p.until(i, end, func(string) bool { return false })
+			}
+
+		case asciidocBlockTitleRegex.MatchString(text):
+			p.paragraph([]span{{p.lines[i].start + 1, p.lines[i].end}})
+			i++
+			continue
+
+		// Literal paragraphs are indented
+		case asciidocSkippedStyles[style] || text[0] == ' ' || text[0] == '\t':
+			i = p.until(i, end, asciidocDelimiterRegex.MatchString)
+
+		case asciidocListRegex.MatchString(text):
+			i = p.item(i, end, len(asciidocListRegex.FindString(text)))
+
+		case asciidocTermRegex.MatchString(text):
+			match := asciidocTermRegex.FindStringSubmatchIndex(text)
+			start := p.lines[i].start
+			p.paragraph([]span{{start + match[2], start + match[3]}})
+			i = p.item(i, end, match[1])
+
+		default:
+			next := p.until(i+1, end, asciidocDelimiterRegex.MatchString)
+			first := p.lines[i]
+			first.start += len(asciidocAdmonitionRegex.FindString(text))
+			p.paragraph(append([]span{first}, p.lines[i+1:next]...))
+			i = next
+		}
+		style = ""
+	}
+}
+
+// item adds the text of a list item, which starts width bytes into line i
+// and continues up to a blank line, the next item or a list continuation.
+func (p *asciidocParser) item(i, end, width int) int {
+	next := p.until(i+1, end, func(text string) bool {
+		return text == "+" || asciidocListRegex.MatchString(text) || asciidocTermRegex.MatchString(text) ||
+			asciidocDelimiterRegex.MatchString(text) || asciidocBlockAttrRegex.MatchString(text)
+	})
+	lines := []span{{p.lines[i].start + width, p.lines[i].end}}
+	for _, line := range p.lines[i+1 : next] {
+		for line.start < line.end && (p.doc.text[line.start] == ' ' || p.doc.text[line.start] == '\t') {
+			line.start++
+		}
+		lines = append(lines, line)
+	}
+	p.paragraph(lines)
+	return next
+}
+
+// table adds every cell of a table as a segment of its own. Cells with the
+// literal style are skipped.
+func (p *asciidocParser) table(start, end int, separator byte) {
+	cell := segment{}
+	literal := false
+	flush := func() {
+		if text := asciidocInline(cell); !literal && hasProse(text.text) {
+			p.segments = append(p.segments, text)
+		}
+		cell = segment{}
+	}
+	add := func(start, end int) {
+		text := p.doc.text[start:end]
+		start += len(text) - len(strings.TrimLeft(text, " \t"))
+		end -= len(text) - len(strings.TrimRight(text, " \t"))
+		if start >= end {
+			return
+		}
+		if len(cell.text) > 0 {
+			cell.join(" ", start-1)
+		}
+		cell.add(p.doc, start, end)
+	}
+
+	for i := start; i < end; i++ {
+		line := p.lines[i]
+		if line.start == line.end {
+			flush()
+			continue
+		}
+
+		from := line.start
+		for j := line.start; j < line.end; j++ {
+			if p.doc.text[j] != separator || (j > line.start && p.doc.text[j-1] == '\\') {
+				continue
+			}
+			// A cell spec like "2+" or "a" comes right before the separator
+			spec := j
+			if match := asciidocCellSpecRegex.FindStringSubmatchIndex(p.doc.text[from:j]); match != nil {
+				spec = from + match[2]
+			}
+			add(from, spec)
+			flush()
+			literal = strings.HasSuffix(p.doc.text[spec:j], "l")
+			from = j + 1
+		}
+		add(from, line.end)
+	}
+	flush()
+}
+
+// paragraph adds lines as a segment, joined by spaces. The " +" of hard line
+// breaks is dropped.
+func (p *asciidocParser) paragraph(lines []span) {
+	s := segment{}
+	for i, line := range lines {
+		if strings.HasSuffix(p.doc.text[line.start:line.end], " +") {
+			line.end -= 2
+		}
+		if i > 0 {
+			s.join(" ", line.start-1)
+		}
+		s.add(p.doc, line.start, line.end)
+	}
+
+	if text := asciidocInline(s); hasProse(text.text) {
+		p.segments = append(p.segments, text)
+	}
+}
+
+// asciidocInline removes AsciiDoc inline markup: bold, italic and highlight
+// markers, anchors, escapes and the targets of links and cross references.
+// Monospace, passthroughs, attribute references and other inline macros are
+// replaced by a placeholder, and footnotes are dropped.
+func asciidocInline(s segment) segment {
+	result := segment{}
+	text := s.text
+
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+	}
+	before := func(i int) rune {
+		if i == 0 {
+			return ' '
+		}
+		r, _ := utf8.DecodeLastRuneInString(text[:i])
+		return r
+	}
+	after := func(i int) rune {
+		if i >= len(text) {
+			return ' '
+		}
+		r, _ := utf8.DecodeRuneInString(text[i:])
+		return r
+	}
+	placeholder := func(start, end int, text string) {
+		result.replace(text, s.offsets[start], s.offsets[end-1]+1)
+	}
+	// closing returns the end of a span of markup starting with n markers at
+	// i, or -1. Single markers are constrained to word boundaries.
+	closing := func(i, n int) int {
+		marker := text[i : i+n]
+		if n == 1 && (isWord(before(i)) || unicode.IsSpace(after(i+1))) {
+			return -1
+		}
+		for j := i + n + 1; j+n <= len(text); j++ {
+			if text[j:j+n] != marker || unicode.IsSpace(before(j)) {
+				continue
+			}
+			if n == 1 && isWord(after(j+1)) {
+				continue
+			}
+			return j + n
+		}
+		return -1
+	}
+	// label returns the text of a link, without attributes like window=_blank.
+	label := func(start, end int) segment {
+		value := text[start:end]
+		if comma := strings.IndexByte(value, ','); comma != -1 && strings.Contains(value[comma:], "=") {
+			value = value[:comma]
+		}
+		end = start + len(strings.TrimRight(value, "^ "))
+		return asciidocInline(s.slice(start, end))
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]):
+			result.append(s.slice(i+1, i+2))
+			i += 2
+			continue
+
+		case c == '`' || c == '+' && !isWord(before(i)):
+			n := min(runLength(text, i), 3)
+			if c == '`' {
+				n = min(n, 2)
+			}
+			if end := closing(i, n); end != -1 {
+				placeholder(i, end, codePlaceholder)
+				i = end
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '#':
+			n := min(runLength(text, i), 2)
+			if end := closing(i, n); end != -1 {
+				result.append(asciidocInline(s.slice(i+n, end-n)))
+				i = end
+				continue
+			}
+
+		case c == '{' && asciidocAttributeRef.MatchString(text[i:]):
+			end := i + len(asciidocAttributeRef.FindString(text[i:]))
+			placeholder(i, end, codePlaceholder)
+			i = end
+			continue
+
+		case c == '[' && asciidocAnchorRegex.MatchString(text[i:]):
+			i += len(asciidocAnchorRegex.FindString(text[i:]))
+			continue
+
+		case c == '[' && asciidocRoleRegex.MatchString(text[i:]):
+			i += len(asciidocRoleRegex.FindString(text[i:])) - 1
+			continue
+
+		case c == '<' && asciidocXrefRegex.MatchString(text[i:]):
+			match := asciidocXrefRegex.FindStringSubmatchIndex(text[i:])
+			if match[4] != -1 {
+				result.append(label(i+match[4], i+match[5]))
+			} else {
+				placeholder(i, i+match[1], codePlaceholder)
+			}
+			i += match[1]
+			continue
+
+		case !isWord(before(i)) && asciidocURLRegex.MatchString(text[i:]):
+			end := i + len(asciidocURLRegex.FindString(text[i:]))
+			if end < len(text) && text[end] == '[' {
+				if close := strings.IndexByte(text[end:], ']'); close != -1 {
+					if close > 1 {
+						result.append(label(end+1, end+close))
+						i = end + close + 1
+						continue
+					}
+					end += close + 1
+				}
+			}
+			placeholder(i, end, urlPlaceholder)
+			i = end
+			continue
+
+		case !isWord(before(i)) && asciidocMacroRegex.MatchString(text[i:]):
+			match := asciidocMacroRegex.FindStringSubmatchIndex(text[i:])
+			switch name := text[i+match[2] : i+match[3]]; {
+			case name == "footnote" || name == "footnoteref" || name == "indexterm":
+			case (name == "link" || name == "mailto" || name == "xref") && match[7] > match[6]:
+				result.append(label(i+match[6], i+match[7]))
+			case name == "link" || name == "mailto":
+				placeholder(i, i+match[1], urlPlaceholder)
+			default:
+				placeholder(i, i+match[1], codePlaceholder)
+			}
+			i += match[1]
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		result.append(s.slice(i, i+size))
+		i += size
+	}
+
+	return result
+}
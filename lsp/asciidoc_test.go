@@ -0,0 +1,65 @@
+package lsp
+
+import "testing"
+
+func TestAsciidocSegments(t *testing.T) {
+	text := "= Document Title\nJane Doe <jane@example.com>\n:toc: left\n\n" +
+		"== First Section\n\n" +
+		"This is *bold* with `code` and {product}.\n" +
+		"See https://example.com[the site] and <<intro,the intro>>.\n\n" +
+		"NOTE: Admonitions are checked.\n\n" +
+		"[source,go]\n----\nfmt.Println(\"skip\")\n----\n\n" +
+		"include::chapter.adoc[]\n\n" +
+		".A block title\n====\nExample content.\n====\n\n" +
+		"* First item\ncontinues here.\n\n" +
+		"|===\n|Name |Description\n\n|foo\n2+|Merged cell.\nl|literal cell\n|===\n\n" +
+		" literal paragraph\n"
+
+	expected := []Sentence{
+		{Text: "Document Title", Range: Range{Position{0, 2}, Position{0, 16}}},
+		{Text: "First Section", Range: Range{Position{4, 3}, Position{4, 16}}},
+		{Text: "This is bold with ⟦code⟧ and ⟦code⟧.", Range: Range{Position{6, 0}, Position{6, 41}}},
+		{Text: "See the site and the intro.", Range: Range{Position{7, 0}, Position{7, 58}}},
+		{Text: "Admonitions are checked.", Range: Range{Position{9, 6}, Position{9, 30}}},
+		{Text: "A block title", Range: Range{Position{18, 1}, Position{18, 14}}},
+		{Text: "Example content.", Range: Range{Position{20, 0}, Position{20, 16}}},
+		{Text: "First item continues here.", Range: Range{Position{23, 2}, Position{24, 15}}},
+		{Text: "Name", Range: Range{Position{27, 1}, Position{27, 5}}},
+		{Text: "Description", Range: Range{Position{27, 7}, Position{27, 18}}},
+		{Text: "foo", Range: Range{Position{29, 1}, Position{29, 4}}},
+		{Text: "Merged cell.", Range: Range{Position{30, 3}, Position{30, 15}}},
+	}
+
+	result := parseSegments(text, parseOptions{language: defaultLanguage}, asciidocSegments)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i := range expected {
+		testSentence(t, result[i], expected[i])
+	}
+}
+
+func TestAsciidocInline(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected string
+	}{
+		{"*Bold* and __un__constrained", "Bold and unconstrained"},
+		{"snake_case_name stays", "snake_case_name stays"},
+		{"Press kbd:[Ctrl+C] now", "Press ⟦code⟧ now"},
+		{"Visit https://example.com today", "Visit ⟦url⟧ today"},
+		{"A link:guide.html[guide,window=_blank] here", "A guide here"},
+		{"A note.footnote:[Skipped.]", "A note."},
+		{"[[anchor]]Text with [.role]#highlight#", "Text with highlight"},
+		{"An \\*escaped* star in C++", "An *escaped* star in C++"},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(test.Text)
+		s := segment{}
+		s.add(doc, 0, len(test.Text))
+		if result := asciidocInline(s); result.text != test.Expected {
+			t.Errorf("Expected %s, got %s", test.Expected, result.text)
+		}
+	}
+}
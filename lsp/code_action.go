@@ -0,0 +1,71 @@
+package lsp
+
+type CodeActionRequest struct {
+	Request
+	Params CodeActionParams `json:"params"`
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type CodeActionResponse struct {
+	Response
+	Result []CodeAction `json:"result"`
+}
+
+const CodeActionKindQuickFix = "quickfix"
+
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        string         `json:"kind"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool           `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+// CodeAction offers the fixes of the jalsa diagnostics in the request as
+// quick fixes, and in markdown documents directives that silence them.
+func (s *Server) CodeAction(request *CodeActionRequest) *CodeActionResponse {
+	response := &CodeActionResponse{
+		Response: Response{RPC: "2.0", ID: request.ID},
+		Result:   []CodeAction{},
+	}
+
+	uri := request.Params.TextDocument.URI
+	disable := []CodeAction{}
+	for _, diagnostic := range request.Params.Context.Diagnostics {
+		if diagnostic.Source != "jalsa" {
+			continue
+		}
+		if s.isMarkdown(uri) {
+			disable = append(disable, disableActions(uri, s.Files[uri], diagnostic, s.exclusions)...)
+		}
+		if diagnostic.Data == nil || diagnostic.Data.Fix == nil {
+			continue
+		}
+
+		response.Result = append(response.Result, CodeAction{
+			Title:       "Fix: " + diagnostic.Data.Title,
+			Kind:        CodeActionKindQuickFix,
+			Diagnostics: []Diagnostic{diagnostic},
+			IsPreferred: true,
+			Edit: &WorkspaceEdit{
+				Changes: map[string][]TextEdit{uri: {*diagnostic.Data.Fix}},
+			},
+		})
+	}
+	response.Result = append(response.Result, disable...)
+
+	return response
+}
@@ -0,0 +1,437 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commentSyntax describes the comments and string literals of a programming
+// language, so that comment markers inside strings aren't read as comments.
+type commentSyntax struct {
+	// line are the markers of line comments, longest first, like "///"
+	// before "//".
+	line []string
+	// block are the opening and closing markers of block comments.
+	block [][2]string
+	// strings are the delimiters of string literals, longest first.
+	strings []string
+
+	// hashAfterSpace only starts "#" comments at the start of a line or
+	// after a space, like in shell scripts where $# isn't a comment.
+	hashAfterSpace bool
+	// quotesStartValues only opens strings at the start of a value, so that
+	// the apostrophe in a plain YAML scalar doesn't.
+	quotesStartValues bool
+	// docstrings checks Python docstrings as comments.
+	docstrings bool
+	// rust skips raw strings like r#"…"# and char literals like '"'.
+	rust bool
+}
+
+var (
+	pythonSyntax = &commentSyntax{
+		line:       []string{"#"},
+		strings:    []string{`"""`, `'''`, `"`, `'`},
+		docstrings: true,
+	}
+	javascriptSyntax = &commentSyntax{
+		line:    []string{"//"},
+		block:   [][2]string{{"/*", "*/"}},
+		strings: []string{`"`, `'`, "`"},
+	}
+	rustSyntax = &commentSyntax{
+		line:    []string{"///", "//!", "//"},
+		block:   [][2]string{{"/*", "*/"}},
+		strings: []string{`"`},
+		rust:    true,
+	}
+	shellSyntax = &commentSyntax{
+		line:           []string{"#"},
+		strings:        []string{`"`, `'`},
+		hashAfterSpace: true,
+	}
+	yamlSyntax = &commentSyntax{
+		line:              []string{"#"},
+		strings:           []string{`"`, `'`},
+		hashAfterSpace:    true,
+		quotesStartValues: true,
+	}
+)
+
+func init() {
+	languages := map[*commentSyntax][]string{
+		pythonSyntax:     {"python"},
+		javascriptSyntax: {"javascript", "javascriptreact", "typescript", "typescriptreact"},
+		rustSyntax:       {"rust"},
+		shellSyntax:      {"shellscript", "sh", "bash", "zsh"},
+		yamlSyntax:       {"yaml"},
+	}
+	for syntax, ids := range languages {
+		syntax := syntax
+		for _, id := range ids {
+			registerParser(id, func(doc *document) []segment {
+				return commentSegments(doc, syntax)
+			})
+		}
+	}
+}
+
+const (
+	commentLine = iota
+	commentBlock
+	commentDocstring
+)
+
+// comment is a comment found by lexComments. lines are the spans of its text
+// without markers. Line comments have one line until they are merged.
+type comment struct {
+	kind     int
+	lines    []span
+	marker   string
+	column   int
+	line     int
+	trailing bool
+}
+
+// lexComments returns the comments of a document, skipping string literals.
+func lexComments(doc *document, syntax *commentSyntax) []comment {
+	comments := []comment{}
+	text := doc.text
+
+	// lineStart returns the offset of the start of the line around i.
+	lineStart := func(i int) int {
+		return strings.LastIndexByte(text[:i], '\n') + 1
+	}
+
+	for i := 0; i < len(text); {
+		if syntax.rust {
+			if n := rustLiteral(text[i:], i > 0 && isWordByte(text[i-1])); n > 0 {
+				i += n
+				continue
+			}
+		}
+
+		if delimiter := syntax.stringAt(text, i); delimiter != "" {
+			end := stringEnd(text, i+len(delimiter), delimiter)
+			if syntax.docstrings && len(delimiter) == 3 && isDocstring(text, i) {
+				start := i + len(delimiter)
+				comments = append(comments, comment{
+					kind:  commentDocstring,
+					lines: blockLines(doc, start, max(end-len(delimiter), start), false),
+					line:  doc.position(i).Line,
+				})
+			}
+			i = end
+			continue
+		}
+
+		if marker, close := syntax.blockAt(text, i); marker != "" {
+			start := i + len(marker)
+			end := strings.Index(text[start:], close)
+			if end == -1 {
+				end = len(text)
+			} else {
+				end += start
+			}
+			// Doc comments like /** */ are marked with their extra star
+			if strings.HasPrefix(text[start:end], "*") {
+				marker += "*"
+			}
+			comments = append(comments, comment{
+				kind:   commentBlock,
+				lines:  blockLines(doc, start, end, true),
+				marker: marker,
+				line:   doc.position(i).Line,
+			})
+			i = min(end+len(close), len(text))
+			continue
+		}
+
+		if marker := syntax.lineAt(text, i); marker != "" {
+			end := strings.IndexByte(text[i:], '\n')
+			if end == -1 {
+				end = len(text)
+			} else {
+				end += i
+			}
+			// A shebang isn't a comment
+			if i == 0 && strings.HasPrefix(text, "#!") {
+				i = end
+				continue
+			}
+			start := i + len(marker)
+			for start < end && (text[start] == ' ' || text[start] == '\t') {
+				start++
+			}
+			body := strings.TrimRight(text[start:end], " \t\r")
+			comments = append(comments, comment{
+				kind:     commentLine,
+				lines:    []span{{start, start + len(body)}},
+				marker:   marker,
+				column:   i - lineStart(i),
+				line:     doc.position(i).Line,
+				trailing: strings.TrimSpace(text[lineStart(i):i]) != "",
+			})
+			i = end
+			continue
+		}
+
+		i++
+	}
+
+	return comments
+}
+
+func (s *commentSyntax) stringAt(text string, i int) string {
+	for _, delimiter := range s.strings {
+		if !strings.HasPrefix(text[i:], delimiter) {
+			continue
+		}
+		if s.quotesStartValues && i > 0 && !strings.ContainsRune(" \t\n:[{,-", rune(text[i-1])) {
+			return ""
+		}
+		return delimiter
+	}
+	return ""
+}
+
+func (s *commentSyntax) blockAt(text string, i int) (string, string) {
+	for _, block := range s.block {
+		if strings.HasPrefix(text[i:], block[0]) {
+			return block[0], block[1]
+		}
+	}
+	return "", ""
+}
+
+func (s *commentSyntax) lineAt(text string, i int) string {
+	for _, marker := range s.line {
+		if !strings.HasPrefix(text[i:], marker) {
+			continue
+		}
+		if s.hashAfterSpace && i > 0 && text[i-1] != ' ' && text[i-1] != '\t' && text[i-1] != '\n' {
+			return ""
+		}
+		return marker
+	}
+	return ""
+}
+
+// stringEnd returns the offset after the string literal whose text starts at
+// i. Strings with a one character delimiter other than a backtick end at the
+// end of the line when they aren't closed.
+func stringEnd(text string, i int, delimiter string) int {
+	multiline := len(delimiter) > 1 || delimiter == "`"
+	for i < len(text) {
+		switch {
+		case text[i] == '\\':
+			i += 2
+			continue
+		case text[i] == '\n' && !multiline:
+			return i
+		case strings.HasPrefix(text[i:], delimiter):
+			return i + len(delimiter)
+		}
+		i++
+	}
+	return len(text)
+}
+
+var rustLiteralRegex = regexp.MustCompile(`^(?:r(#*)"|b?'(?:\\.[^']*|[^'\\\n])')`)
+
+// rustLiteral returns the length of the raw string or char literal at the
+// start of text, or 0. Lifetimes like 'a aren't literals.
+func rustLiteral(text string, afterWord bool) int {
+	match := rustLiteralRegex.FindStringSubmatchIndex(text)
+	if match == nil || afterWord && text[0] != '\'' {
+		return 0
+	}
+	if match[2] == -1 {
+		return match[1]
+	}
+	closing := `"` + text[match[2]:match[3]]
+	if end := strings.Index(text[match[1]:], closing); end != -1 {
+		return match[1] + end + len(closing)
+	}
+	return len(text)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || isLetter(c)
+}
+
+// isDocstring reports whether the string starting at i is the first
+// statement of a module, class or function.
+func isDocstring(text string, i int) bool {
+	start := strings.LastIndexByte(text[:i], '\n') + 1
+	if strings.TrimLeft(text[start:i], " \trRuU") != "" {
+		return false
+	}
+
+	// The code before is a line ending with ":" or there is none
+	for _, line := range reverseLines(text[:start]) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasSuffix(line, ":")
+	}
+	return true
+}
+
+func reverseLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// blockLines returns the lines of the text of a block comment or docstring
+// between start and end, without indentation. The "*" starting the lines of
+// comments like /** */ is dropped when stars is set.
+func blockLines(doc *document, start, end int, stars bool) []span {
+	lines := []span{}
+	for i := start; i <= end; {
+		lineEnd := strings.IndexByte(doc.text[i:end], '\n')
+		if lineEnd == -1 {
+			lineEnd = end
+		} else {
+			lineEnd += i
+		}
+
+		from := i
+		for from < lineEnd && (doc.text[from] == ' ' || doc.text[from] == '\t') {
+			from++
+		}
+		if stars {
+			for from < lineEnd && doc.text[from] == '*' {
+				from++
+			}
+			if from < lineEnd && doc.text[from] == ' ' {
+				from++
+			}
+		}
+		to := from + len(strings.TrimRight(doc.text[from:lineEnd], " \t\r"))
+		lines = append(lines, span{from, to})
+		i = lineEnd + 1
+	}
+	return lines
+}
+
+var (
+	codeKeywordRegex = regexp.MustCompile(`^(?:if|for|while|switch|catch|elif|else|return|def|class|func|fn|function|import|from|let|const|var|pub|use|package|echo|export|print|async|await|try)\b(?:\s*[(){}\[\];=:]|\s*[\w.$]+\s*(?:[(){}\[\];=]|::)|.*[()\[\]<>=].*:$)`)
+	codeStatement    = regexp.MustCompile(`^[\w.$\[\]"']+\s*(?:=|:=|\+=|-=|\*=|/=|==|!=|=>|->)\s*\S|^[\w.$]+\(.*\)[;,]?$|^</?\w+[^>]*>$|^[a-z_][\w-]*:(?:\s+\S+)?$|^#include\b`)
+	jsdocParamRegex  = regexp.MustCompile(`^@(?:param|arg|argument|property|prop)\s+(?:\{[^}]*\}\s*)?(?:\[[^\]]*\]|\S+)\s*(?:-\s*)?`)
+	jsdocTextRegex   = regexp.MustCompile(`^@(?:returns?|throws|exception|yields|deprecated|since|todo|summary|description)\b\s*(?:\{[^}]*\}\s*)?(?:-\s*)?`)
+	commentInline    = []Exclusion{{Name: "code", Inline: regexp.MustCompile("`[^`]+`|\\{@\\w+[^}]*\\}|https?://\\S+")}}
+)
+
+// looksLikeCode reports whether a line of a comment is commented-out code
+// rather than prose: it ends like a statement, starts with a keyword and has
+// code punctuation, assigns or calls something, or is a tag.
+func looksLikeCode(line string) bool {
+	if strings.HasSuffix(line, ";") || strings.HasSuffix(line, "{") || strings.HasSuffix(line, "}") || strings.HasSuffix(line, "(") {
+		return true
+	}
+	// A keyword starts code when punctuation follows it or the word after
+	// it, and prose like "if the file is missing (for example on CI)." ends
+	// with a period
+	if codeKeywordRegex.MatchString(line) && !endsSentence(line) || codeStatement.MatchString(line) {
+		return true
+	}
+
+	symbols := 0
+	for _, c := range line {
+		if strings.ContainsRune("{}[];=<>&|$", c) {
+			symbols++
+		}
+	}
+	return symbols > 1 && symbols*10 > len(line)
+}
+
+// endsSentence reports whether line ends with terminal punctuation, maybe
+// followed by closing quotes and brackets.
+func endsSentence(line string) bool {
+	line = strings.TrimRight(line, "\"')]")
+	return strings.HasSuffix(line, ".") || strings.HasSuffix(line, "?") || strings.HasSuffix(line, "!")
+}
+
+// commentSegments extracts the prose of the comments and docstrings of a
+// source file. Consecutive line comments in the same column are one
+// paragraph. Commented-out code, JSDoc tags other than descriptions and
+// doctests are skipped.
+func commentSegments(doc *document, syntax *commentSyntax) []segment {
+	comments := []comment{}
+	for _, c := range lexComments(doc, syntax) {
+		if n := len(comments); n > 0 && c.kind == commentLine && !c.trailing {
+			previous := &comments[n-1]
+			if previous.kind == commentLine && !previous.trailing && previous.marker == c.marker &&
+				previous.column == c.column && previous.line+len(previous.lines) == c.line {
+				previous.lines = append(previous.lines, c.lines...)
+				continue
+			}
+		}
+		comments = append(comments, c)
+	}
+
+	segments := []segment{}
+	for _, c := range comments {
+		segments = append(segments, c.paragraphs(doc)...)
+	}
+	return segments
+}
+
+// paragraphs splits the lines of a comment at blank lines and code.
+func (c comment) paragraphs(doc *document) []segment {
+	segments := []segment{}
+	lines := []span{}
+	flush := func() {
+		s := segment{}
+		s.addLines(doc, lines)
+		if s = excludeInline(s, commentInline); hasProse(s.text) {
+			segments = append(segments, s)
+		}
+		lines = []span{}
+	}
+
+	jsdoc := c.marker == "/**"
+	skipping := false
+	for _, line := range c.lines {
+		text := doc.text[line.start:line.end]
+		switch {
+		case text == "":
+			flush()
+			skipping = false
+			continue
+
+		case jsdoc && strings.HasPrefix(text, "@"):
+			// Only the descriptions of tags are prose
+			flush()
+			match := jsdocParamRegex.FindString(text)
+			if match == "" {
+				match = jsdocTextRegex.FindString(text)
+			}
+			skipping = match == ""
+			line.start += len(match)
+
+		case c.kind == commentDocstring && strings.HasPrefix(text, ">>>"):
+			// A doctest runs up to the next blank line
+			flush()
+			skipping = true
+
+		case skipping:
+
+		case looksLikeCode(text):
+			flush()
+			continue
+		}
+
+		if !skipping {
+			lines = append(lines, line)
+		}
+	}
+	flush()
+
+	return segments
+}
@@ -0,0 +1,135 @@
+package lsp
+
+import "testing"
+
+func TestCommentSegments(t *testing.T) {
+	tests := []struct {
+		Syntax   *commentSyntax
+		Text     string
+		Expected []Sentence
+	}{
+		{
+			Syntax: pythonSyntax,
+			Text: `#!/usr/bin/env python
+"""Module docstring is checked.
+
+>>> add(1, 2)
+3
+"""
+import os
+
+# This comment spans
+# two lines.
+x = "# not a comment"  # Trailing comment here.
+# result = compute(x)
+
+def add(a, b):
+    '''Add two numbers together.'''
+    return a + b
+`,
+			Expected: []Sentence{
+				{Text: "Module docstring is checked.", Range: Range{Position{1, 3}, Position{1, 31}}},
+				{Text: "This comment spans two lines.", Range: Range{Position{8, 2}, Position{9, 12}}},
+				{Text: "Trailing comment here.", Range: Range{Position{10, 25}, Position{10, 47}}},
+				{Text: "Add two numbers together.", Range: Range{Position{14, 7}, Position{14, 32}}},
+			},
+		},
+		{
+			Syntax: javascriptSyntax,
+			Text: `/**
+ * Formats a name for display.
+ * @param {string} name - The name to format.
+ * @returns The formatted name.
+ * @example
+ * format("x")
+ */
+const url = "http://example.com"; // Teh base URL.
+// if (x) { y(); }
+const s = ` + "`" + `template // not a comment` + "`" + `;
+`,
+			Expected: []Sentence{
+				{Text: "Formats a name for display.", Range: Range{Position{1, 3}, Position{1, 30}}},
+				{Text: "The name to format.", Range: Range{Position{2, 26}, Position{2, 45}}},
+				{Text: "The formatted name.", Range: Range{Position{3, 12}, Position{3, 31}}},
+				{Text: "Teh base URL.", Range: Range{Position{7, 37}, Position{7, 50}}},
+			},
+		},
+		{
+			Syntax: rustSyntax,
+			Text: `//! Crate level docs are here.
+/// Returns the first item.
+fn first<'a>(x: &'a str) -> char { '"' }
+let r = r#"// not a comment"#;
+`,
+			Expected: []Sentence{
+				{Text: "Crate level docs are here.", Range: Range{Position{0, 4}, Position{0, 30}}},
+				{Text: "Returns the first item.", Range: Range{Position{1, 4}, Position{1, 27}}},
+			},
+		},
+		{
+			Syntax: shellSyntax,
+			Text:   "# Load the config file and fall back to the defaults\n# if the file is missing (for example on CI).\nload_config\n",
+			Expected: []Sentence{
+				{Text: "Load the config file and fall back to the defaults if the file is missing (for example on CI).", Range: Range{Position{0, 2}, Position{1, 45}}},
+			},
+		},
+		{
+			Syntax: javascriptSyntax,
+			Text:   "// return the result (or null).\nreturn cache.get(key);\n",
+			Expected: []Sentence{
+				{Text: "return the result (or null).", Range: Range{Position{0, 3}, Position{0, 31}}},
+			},
+		},
+		{
+			Syntax: yamlSyntax,
+			Text:   "# Settings of the app.\nname: it's # The name.\ncolor: \"#fff\"\n# enabled: true\n",
+			Expected: []Sentence{
+				{Text: "Settings of the app.", Range: Range{Position{0, 2}, Position{0, 22}}},
+				{Text: "The name.", Range: Range{Position{1, 13}, Position{1, 22}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		syntax := test.Syntax
+		result := parseSegments(test.Text, parseOptions{language: defaultLanguage}, func(doc *document) []segment {
+			return commentSegments(doc, syntax)
+		})
+		if len(result) != len(test.Expected) {
+			t.Errorf("Expected %d sentences, got %d: %v", len(test.Expected), len(result), result)
+			continue
+		}
+		for i := range test.Expected {
+			testSentence(t, result[i], test.Expected[i])
+		}
+	}
+}
+
+func TestLooksLikeCode(t *testing.T) {
+	tests := []struct {
+		Line     string
+		Expected bool
+	}{
+		{"result = compute(x)", true},
+		{"if (x) { y(); }", true},
+		{"print(value)", true},
+		{"enabled: true", true},
+		{"Returns the value (or nil).", false},
+		{"TODO: handle the error case", false},
+		{"If the cache is empty, load it.", false},
+		{"if the file is missing (for example on CI).", false},
+		{"return the result (or null).", false},
+		{"for example:", false},
+		{"for i in range(3):", true},
+		{"if x > 0:", true},
+		{"else:", true},
+		{"let total = 0", true},
+		{"use std::io", true},
+	}
+
+	for _, test := range tests {
+		if result := looksLikeCode(test.Line); result != test.Expected {
+			t.Errorf("Expected %v for %q, got %v", test.Expected, test.Line, result)
+		}
+	}
+}
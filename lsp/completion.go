@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CompletionRequest struct {
+	Request
+	Params CompletionParams `json:"params"`
+}
+
+type CompletionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type CompletionResponse struct {
+	Response
+	Result CompletionList `json:"result"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+const CompletionItemKindText = 1
+
+type CompletionItem struct {
+	Label      string    `json:"label"`
+	Kind       int       `json:"kind"`
+	Detail     string    `json:"detail,omitempty"`
+	SortText   string    `json:"sortText,omitempty"`
+	FilterText string    `json:"filterText,omitempty"`
+	TextEdit   *TextEdit `json:"textEdit,omitempty"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type CompletionOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+const maxSuggestions = 8
+
+// Completion offers spelling suggestions for the word under the cursor when
+// the offline dictionary doesn't know it. Without a full word list, like
+// /usr/share/dict/words, nothing is offered, as most words would look
+// misspelled.
+func (s *Server) Completion(request *CompletionRequest) *CompletionResponse {
+	response := &CompletionResponse{
+		Response: Response{RPC: "2.0", ID: request.ID},
+		Result:   CompletionList{Items: []CompletionItem{}},
+	}
+
+	if !s.dictionary.Complete() {
+		return response
+	}
+
+	position := request.Params.Position
+	lines := strings.Split(s.Files[request.Params.TextDocument.URI], "\n")
+	if position.Line >= len(lines) {
+		return response
+	}
+
+	word, start, end := wordAt(lines[position.Line], position.Character)
+	if word == "" || s.dictionary.Contains(word) {
+		return response
+	}
+
+	wordRange := Range{
+		Start: Position{Line: position.Line, Character: start},
+		End:   Position{Line: position.Line, Character: end},
+	}
+	for i, suggestion := range s.dictionary.Suggest(word, maxSuggestions) {
+		response.Result.Items = append(response.Result.Items, CompletionItem{
+			Label:      suggestion,
+			Kind:       CompletionItemKindText,
+			Detail:     "jalsa: spelling",
+			SortText:   fmt.Sprintf("%02d", i),
+			FilterText: word,
+			TextEdit:   &TextEdit{Range: wordRange, NewText: suggestion},
+		})
+	}
+
+	return response
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func completionLabels(server *Server, line, character int) []string {
+	response := server.Completion(&CompletionRequest{
+		Params: CompletionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///test.md"},
+			Position:     Position{Line: line, Character: character},
+		},
+	})
+	labels := []string{}
+	for _, item := range response.Result.Items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+func TestCompletion(t *testing.T) {
+	wordList := filepath.Join(t.TempDir(), "words")
+	if err := os.WriteFile(wordList, []byte("diagnostics\nconfigured\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestServer()
+	server.dictionary = NewDictionary(DictionaryConfig{
+		Words:     []string{"Kubernetes"},
+		WordLists: []string{wordList},
+	})
+	server.Files["file:///test.md"] = "The diagnostics are configured for Kubernetes.\nDeploy to Kubernets with sentense"
+
+	tests := []struct {
+		Name      string
+		Line      int
+		Character int
+		Expected  string
+	}{
+		{"known word", 0, 6, ""},
+		{"project term", 1, 12, "Kubernetes"},
+		{"unknown word", 1, 28, "sentence"},
+		{"past the end of the line", 1, 100, "sentence"},
+		{"past the end of the file", 5, 0, ""},
+	}
+
+	for _, test := range tests {
+		labels := completionLabels(server, test.Line, test.Character)
+		switch {
+		case test.Expected == "" && len(labels) > 0:
+			t.Errorf("%s: expected no suggestions, got %v", test.Name, labels)
+		case test.Expected != "" && (len(labels) == 0 || labels[0] != test.Expected):
+			t.Errorf("%s: expected %s first, got %v", test.Name, test.Expected, labels)
+		}
+	}
+
+	if labels := completionLabels(server, 0, 38); len(labels) > 0 {
+		t.Errorf("Expected no suggestions for a known project term, got %v", labels)
+	}
+}
+
+func TestCompletionWithoutWordList(t *testing.T) {
+	server := newTestServer()
+	server.dictionary = NewDictionary(DictionaryConfig{WordLists: []string{"does-not-exist.txt"}})
+	server.Files["file:///test.md"] = "The diagnostics are configured with sentense."
+
+	for _, character := range []int{6, 38} {
+		if labels := completionLabels(server, 0, character); len(labels) > 0 {
+			t.Errorf("Expected no suggestions without a word list, got %v", labels)
+		}
+	}
+}
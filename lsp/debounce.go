@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"context"
+	"time"
+)
+
+const defaultDebounce = 500 * time.Millisecond
+
+// pendingCheck is a check scheduled after an edit. Stopping the timer drops a
+// check that hasn't started and cancelling the context stops one in flight.
+type pendingCheck struct {
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (c ModelConfig) debounce() time.Duration {
+	if c.Debounce <= 0 {
+		return defaultDebounce
+	}
+	return time.Duration(c.Debounce) * time.Millisecond
+}
+
+// ScheduleAnalyze checks a document once it has not changed for the debounce
+// delay and hands the diagnostics to publish. A newer edit cancels the
+// previous check, so publish only ever sees results for the latest text.
+func (s *Server) ScheduleAnalyze(fileURI string, publish func(*PublishDiagnosticsNotification)) {
+	s.CancelPending(fileURI)
+
+	// Parse and lint now, on the caller's goroutine, so the check never reads
+	// state that later edits are changing.
+	text := s.Files[fileURI]
+	sentences := s.parse(fileURI, text)
+	diagnostics := s.lint(fileURI, text)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	check := &pendingCheck{cancel: cancel}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	s.pending[fileURI] = check
+	check.timer = time.AfterFunc(s.ModelConfig.debounce(), func() {
+		defer cancel()
+		notification, _ := s.analyze(ctx, fileURI, diagnostics, sentences)
+
+		s.pendingMu.Lock()
+		defer s.pendingMu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		if s.pending[fileURI] == check {
+			delete(s.pending, fileURI)
+		}
+		publish(notification)
+	})
+}
+
+// CancelPending drops the scheduled or running check of a document.
+func (s *Server) CancelPending(fileURI string) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if check, ok := s.pending[fileURI]; ok {
+		check.timer.Stop()
+		check.cancel()
+		delete(s.pending, fileURI)
+	}
+}
@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduleAnalyze(t *testing.T) {
+	server := newTestServer()
+	server.ModelConfig.Debounce = 10
+	server.Files["file:///test.md"] = ""
+
+	var mu sync.Mutex
+	published := 0
+	publish := func(*PublishDiagnosticsNotification) {
+		mu.Lock()
+		defer mu.Unlock()
+		published++
+	}
+
+	server.ScheduleAnalyze("file:///test.md", publish)
+	server.ScheduleAnalyze("file:///test.md", publish)
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if published != 1 {
+		t.Errorf("Expected the second edit to cancel the first check, got %d checks", published)
+	}
+}
+
+func TestScheduleAnalyzeWhileOpening(t *testing.T) {
+	server := newTestServer()
+	server.ModelConfig.Debounce = 1
+	server.OpenDocument(TextDocumentItem{URI: "file:///test.md", LanguageID: "markdown", Text: "![](image.png)"})
+
+	done := make(chan bool)
+	server.ScheduleAnalyze("file:///test.md", func(*PublishDiagnosticsNotification) {
+		close(done)
+	})
+	// The check lints on its own goroutine while documents keep opening
+	for i := 0; i < 100; i++ {
+		server.OpenDocument(TextDocumentItem{URI: "file:///other.md", LanguageID: "markdown"})
+		time.Sleep(50 * time.Microsecond)
+	}
+	<-done
+}
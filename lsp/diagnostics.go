@@ -1,5 +1,10 @@
 package lsp
 
+import (
+	"fmt"
+	"strings"
+)
+
 type PublishDiagnosticsNotification struct {
 	Notification
 	Params PublishDiagnosticsParams `json:"params"`
@@ -17,10 +22,104 @@ const (
 	DiagnosticSeverityHint    = 4
 )
 
+const (
+	DiagnosticTagUnnecessary = 1
+	DiagnosticTagDeprecated  = 2
+)
+
+const (
+	CategoryGrammar     = "grammar"
+	CategorySpelling    = "spelling"
+	CategoryPunctuation = "punctuation"
+	CategoryStyle       = "style"
+	CategoryClarity     = "clarity"
+)
+
+var Categories = []string{CategoryGrammar, CategorySpelling, CategoryPunctuation, CategoryStyle, CategoryClarity}
+
+// CategoryAccessibility is reported by the checks of links and images, not by
+// the model.
+const CategoryAccessibility = "accessibility"
+
 type Diagnostic struct {
-	Range    Range  `json:"range"`
-	Severity int    `json:"severity"`
-	Message  string `json:"message"`
+	Range              Range                          `json:"range"`
+	Severity           int                            `json:"severity"`
+	Code               string                         `json:"code,omitempty"`
+	CodeDescription    *CodeDescription               `json:"codeDescription,omitempty"`
+	Source             string                         `json:"source,omitempty"`
+	Message            string                         `json:"message"`
+	Tags               []int                          `json:"tags,omitempty"`
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+	Data               *DiagnosticData                `json:"data,omitempty"`
+}
+
+// DiagnosticData is kept by the client and sent back with code action
+// requests. Fix is the edit that applies the suggested change.
+type DiagnosticData struct {
+	Title string    `json:"title"`
+	Fix   *TextEdit `json:"fix,omitempty"`
+}
+
+type CodeDescription struct {
+	Href string `json:"href"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type DiagnosticRelatedInformation struct {
+	Location Location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+// DiagnosticConfig is the "diagnostics" section of the config file. Severity
+// maps a category to "error", "warning", "info" or "hint" and RuleDocs is a
+// format string that turns a category into a link to its documentation.
+type DiagnosticConfig struct {
+	Severity map[string]string `json:"severity"`
+	RuleDocs string            `json:"ruleDocs"`
+}
+
+var defaultSeverities = map[string]int{
+	CategoryGrammar:     DiagnosticSeverityError,
+	CategorySpelling:    DiagnosticSeverityError,
+	CategoryPunctuation: DiagnosticSeverityWarning,
+	CategoryStyle:       DiagnosticSeverityInfo,
+	CategoryClarity:     DiagnosticSeverityInfo,
+
+	CategoryAccessibility: DiagnosticSeverityWarning,
+	CategoryCommit:        DiagnosticSeverityWarning,
+	CategoryCaption:       DiagnosticSeverityWarning,
+}
+
+const defaultRuleDocs = "https://github.com/vramana/jalsa/blob/main/docs/rules.md#%s"
+
+func (c DiagnosticConfig) severity(category string) int {
+	switch c.Severity[category] {
+	case "error":
+		return DiagnosticSeverityError
+	case "warning":
+		return DiagnosticSeverityWarning
+	case "info", "information":
+		return DiagnosticSeverityInfo
+	case "hint":
+		return DiagnosticSeverityHint
+	}
+
+	if severity, ok := defaultSeverities[category]; ok {
+		return severity
+	}
+	return DiagnosticSeverityError
+}
+
+func (c DiagnosticConfig) codeDescription(category string) *CodeDescription {
+	ruleDocs := c.RuleDocs
+	if ruleDocs == "" {
+		ruleDocs = defaultRuleDocs
+	}
+	return &CodeDescription{Href: fmt.Sprintf(ruleDocs, category)}
 }
 
 func NewDiagnostics(uri string, diagnostics []Diagnostic) *PublishDiagnosticsNotification {
@@ -36,10 +135,71 @@ func NewDiagnostics(uri string, diagnostics []Diagnostic) *PublishDiagnosticsNot
 	}
 }
 
-func ConvertCheckToDiagnostic(check SentenceCheck) Diagnostic {
-	return Diagnostic{
-		Range:    check.Range,
-		Severity: DiagnosticSeverityError,
-		Message:  check.Explanation + "\n\n" + check.Correction,
+// ConvertCheckToDiagnostics reports every word level change between the
+// sentence and its correction as its own diagnostic, so only the words that
+// are wrong get underlined.
+func ConvertCheckToDiagnostics(config DiagnosticConfig, uri string, sentence Sentence, check SentenceCheck) []Diagnostic {
+	category := check.Category
+	if category == "" {
+		category = CategoryGrammar
+	}
+	if sentence.isDisabled(category) {
+		return []Diagnostic{}
+	}
+	code := category
+	if check.Rule != "" {
+		code += "/" + check.Rule
+	}
+
+	newDiagnostic := func(r Range, message string) Diagnostic {
+		return Diagnostic{
+			Range:           r,
+			Severity:        config.severity(category),
+			Code:            code,
+			CodeDescription: config.codeDescription(category),
+			Source:          "jalsa",
+			Message:         message,
+			RelatedInformation: []DiagnosticRelatedInformation{{
+				Location: Location{URI: uri, Range: check.Range},
+				Message:  "Suggestion: " + check.Correction,
+			}},
+		}
 	}
+
+	diagnostics := []Diagnostic{}
+
+	for _, change := range diffWords(sentence.Text, check.Correction) {
+		diagnostic := newDiagnostic(sentence.rangeOf(change.Start, change.End), change.Message()+"\n\n"+check.Explanation)
+		if change.Replacement == "" {
+			diagnostic.Tags = []int{DiagnosticTagUnnecessary}
+		}
+		if fix := sentenceFix(sentence, change); fix != nil {
+			diagnostic.Data = &DiagnosticData{Title: change.Message(), Fix: fix}
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	if len(diagnostics) == 0 {
+		diagnostic := newDiagnostic(check.Range, check.Explanation+"\n\n"+check.Correction)
+		diagnostic.RelatedInformation = nil
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// sentenceFix returns the document edit applying a change, or nil when the
+// change can't be applied without touching markup that was stripped from the
+// sentence, or would need text replaced by a placeholder.
+func sentenceFix(sentence Sentence, change Change) *TextEdit {
+	original := strings.Fields(sentence.Text[change.FixStart:change.FixEnd])
+	source := strings.Fields(sentence.source(change.FixStart, change.FixEnd))
+	if strings.Join(original, " ") != strings.Join(source, " ") {
+		return nil
+	}
+	if strings.Contains(change.Fix, codePlaceholder) || strings.Contains(change.Fix, urlPlaceholder) {
+		return nil
+	}
+
+	return &TextEdit{Range: sentence.rangeOf(change.FixStart, change.FixEnd), NewText: change.Fix}
 }
@@ -0,0 +1,247 @@
+package lsp
+
+import (
+	_ "embed"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// words.txt lists common English words, the most frequent ones first.
+//
+//go:embed words.txt
+var commonWords string
+
+const defaultWordList = "/usr/share/dict/words"
+
+// DictionaryConfig is the "dictionary" section of the config file. Words are
+// project terms that are always accepted and offered as suggestions, and
+// WordLists are extra files with one word per line.
+type DictionaryConfig struct {
+	Words     []string `json:"words"`
+	WordLists []string `json:"wordLists"`
+}
+
+// Dictionary is an offline word list used to flag misspelled words and to
+// suggest alternatives without asking the model.
+type Dictionary struct {
+	frequency map[string]int
+	custom    map[string]string
+	// complete is set once a full word list is loaded. The embedded list
+	// alone misses too many words to tell what is misspelled.
+	complete bool
+}
+
+func NewDictionary(config DictionaryConfig) *Dictionary {
+	d := &Dictionary{
+		frequency: make(map[string]int),
+		custom:    make(map[string]string),
+	}
+
+	words := strings.Fields(commonWords)
+	for i, word := range words {
+		d.frequency[word] = len(words) - i
+	}
+
+	wordLists := config.WordLists
+	if len(wordLists) == 0 {
+		wordLists = []string{defaultWordList}
+	}
+	for _, path := range wordLists {
+		d.LoadFile(path)
+	}
+
+	d.AddWords(config.Words)
+
+	return d
+}
+
+// LoadFile adds the words of a file with one word per line. Missing files are
+// ignored.
+func (d *Dictionary) LoadFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	d.complete = true
+
+	for _, word := range strings.Fields(string(data)) {
+		word = strings.ToLower(word)
+		if _, ok := d.frequency[word]; !ok {
+			d.frequency[word] = 0
+		}
+	}
+}
+
+// AddWords adds project terms. They keep their casing when suggested.
+func (d *Dictionary) AddWords(words []string) {
+	for _, word := range words {
+		d.custom[strings.ToLower(word)] = word
+	}
+}
+
+// Complete reports whether a full word list was loaded, besides the common
+// words.
+func (d *Dictionary) Complete() bool {
+	return d.complete
+}
+
+func (d *Dictionary) Contains(word string) bool {
+	word = strings.ToLower(word)
+	if _, ok := d.custom[word]; ok {
+		return true
+	}
+	_, ok := d.frequency[word]
+	return ok
+}
+
+type suggestion struct {
+	word      string
+	distance  int
+	custom    bool
+	frequency int
+}
+
+// Suggest returns at most limit words close to word, ranked by edit distance,
+// then project terms first, then by how common the word is.
+func (d *Dictionary) Suggest(word string, limit int) []string {
+	const maxDistance = 2
+
+	lower := strings.ToLower(word)
+	candidates := []suggestion{}
+
+	for candidate, frequency := range d.frequency {
+		if distance := editDistance(lower, candidate, maxDistance); distance <= maxDistance {
+			candidates = append(candidates, suggestion{word: matchCase(word, candidate), distance: distance, frequency: frequency})
+		}
+	}
+	for candidate, original := range d.custom {
+		if distance := editDistance(lower, candidate, maxDistance); distance <= maxDistance {
+			candidates = append(candidates, suggestion{word: original, distance: distance, custom: true})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.custom != b.custom {
+			return a.custom
+		}
+		if a.frequency != b.frequency {
+			return a.frequency > b.frequency
+		}
+		return a.word < b.word
+	})
+
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if len(result) == limit {
+			break
+		}
+		if seen[candidate.word] {
+			continue
+		}
+		seen[candidate.word] = true
+		result = append(result, candidate.word)
+	}
+
+	return result
+}
+
+// matchCase copies the capitalization of word onto suggestion.
+func matchCase(word, suggestion string) string {
+	first, _ := utf8.DecodeRuneInString(word)
+	switch {
+	case len(word) > 1 && strings.ToUpper(word) == word:
+		return strings.ToUpper(suggestion)
+	case unicode.IsUpper(first):
+		r, size := utf8.DecodeRuneInString(suggestion)
+		return string(unicode.ToUpper(r)) + suggestion[size:]
+	default:
+		return suggestion
+	}
+}
+
+// editDistance is the optimal string alignment distance between a and b. It
+// gives up early and returns max+1 once the distance is known to exceed max.
+func editDistance(a, b string, max int) int {
+	s, t := []rune(a), []rune(b)
+	if abs(len(s)-len(t)) > max {
+		return max + 1
+	}
+
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	beforePrevious := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				current[j] = min(current[j], beforePrevious[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		beforePrevious, previous, current = previous, current, beforePrevious
+	}
+
+	return previous[len(t)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func isDictionaryRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '\'' || r == '’'
+}
+
+// wordAt finds the word around character in line and returns it with its
+// start and end byte offsets.
+func wordAt(line string, character int) (string, int, int) {
+	if character > len(line) {
+		character = len(line)
+	}
+
+	start := character
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:start])
+		if !isDictionaryRune(r) {
+			break
+		}
+		start -= size
+	}
+
+	end := character
+	for end < len(line) {
+		r, size := utf8.DecodeRuneInString(line[end:])
+		if !isDictionaryRune(r) {
+			break
+		}
+		end += size
+	}
+
+	word := strings.Trim(line[start:end], "'’")
+	start += strings.Index(line[start:end], word)
+	return word, start, start + len(word)
+}
@@ -0,0 +1,51 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestDictionarySuggest(t *testing.T) {
+	dictionary := NewDictionary(DictionaryConfig{
+		Words:     []string{"Jalsa", "Kubernetes"},
+		WordLists: []string{"does-not-exist.txt"},
+	})
+
+	if !dictionary.Contains("sentence") || !dictionary.Contains("jalsa") {
+		t.Errorf("Expected the dictionary to contain common words and project terms")
+	}
+	if dictionary.Contains("sentense") {
+		t.Errorf("Expected sentense to be flagged")
+	}
+
+	tests := []struct {
+		Word     string
+		Expected string
+	}{
+		{"sentense", "sentence"},
+		{"teh", "the"},
+		{"Becuase", "Because"},
+		{"jalas", "Jalsa"},
+		{"Kubernets", "Kubernetes"},
+	}
+
+	for _, test := range tests {
+		suggestions := dictionary.Suggest(test.Word, 5)
+		if len(suggestions) == 0 || suggestions[0] != test.Expected {
+			t.Errorf("Expected %s for %s, got %v", test.Expected, test.Word, suggestions)
+		}
+	}
+}
+
+func TestWordAt(t *testing.T) {
+	line := "It's a 'sentense' here"
+
+	word, start, end := wordAt(line, 10)
+	if word != "sentense" || start != 8 || end != 16 {
+		t.Errorf("Expected sentense at 8-16, got %s at %d-%d", word, start, end)
+	}
+
+	word, start, end = wordAt(line, 2)
+	if word != "It's" || start != 0 || end != 4 {
+		t.Errorf("Expected It's at 0-4, got %s at %d-%d", word, start, end)
+	}
+}
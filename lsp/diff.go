@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Change is a single span of a sentence that differs from its correction.
+// Start and End are byte offsets into the original sentence text.
+//
+// FixStart, FixEnd and Fix describe the edit of the original text that
+// applies the change. Unlike Start and End they take the whitespace around
+// inserted or removed words along, so applying every fix of a sentence gives
+// back the correction, apart from spacing changes between unchanged words.
+type Change struct {
+	Start       int
+	End         int
+	Original    string
+	Replacement string
+	FixStart    int
+	FixEnd      int
+	Fix         string
+}
+
+type token struct {
+	text  string
+	start int
+	end   int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '’' || r == '-'
+}
+
+// tokenize splits text into words and single punctuation marks. Whitespace
+// is dropped so that changes in spacing alone don't show up as edits.
+func tokenize(text string) []token {
+	tokens := []token{}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		start := i
+		i += size
+		if isWordRune(r) {
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if !isWordRune(r) {
+					break
+				}
+				i += size
+			}
+		}
+		tokens = append(tokens, token{text: text[start:i], start: start, end: i})
+	}
+
+	return tokens
+}
+
+// diffWords returns the word level changes needed to turn original into
+// correction, using the longest common subsequence of their tokens.
+func diffWords(original, correction string) []Change {
+	a := tokenize(original)
+	b := tokenize(correction)
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].text == b[j].text {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	changes := []Change{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i].text == b[j].text {
+			i++
+			j++
+			continue
+		}
+
+		// Collect a run of deletions and insertions into a single change
+		i0, j0 := i, j
+		for i < len(a) || j < len(b) {
+			if i < len(a) && j < len(b) && a[i].text == b[j].text {
+				break
+			}
+			if j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]) {
+				i++
+			} else {
+				j++
+			}
+		}
+
+		change := Change{}
+		switch {
+		case i > i0:
+			change.Start = a[i0].start
+			change.End = a[i-1].end
+		case i < len(a):
+			change.Start = a[i].start
+			change.End = a[i].start
+		case i > 0:
+			change.Start = a[i-1].end
+			change.End = a[i-1].end
+		}
+		change.Original = original[change.Start:change.End]
+		if j > j0 {
+			change.Replacement = correction[b[j0].start:b[j-1].end]
+		}
+
+		// The whitespace around the change in both texts
+		prevEnd, nextStart := 0, len(original)
+		if i0 > 0 {
+			prevEnd = a[i0-1].end
+		}
+		if i < len(a) {
+			nextStart = a[i].start
+		}
+		prevEndC, nextStartC := 0, len(correction)
+		if j0 > 0 {
+			prevEndC = b[j0-1].end
+		}
+		if j < len(b) {
+			nextStartC = b[j].start
+		}
+
+		switch {
+		case i > i0 && j > j0:
+			change.FixStart, change.FixEnd, change.Fix = change.Start, change.End, change.Replacement
+		case j > j0:
+			before := correction[prevEndC:b[j0].start]
+			after := correction[b[j-1].end:nextStartC]
+			switch {
+			case before != "" && i < len(a):
+				change.FixStart, change.Fix = nextStart, change.Replacement+after
+			case before != "":
+				change.FixStart, change.Fix = prevEnd, before+change.Replacement
+			case i0 > 0 && original[prevEnd:nextStart] != "":
+				change.FixStart, change.Fix = prevEnd, change.Replacement
+			case i0 > 0:
+				change.FixStart, change.Fix = prevEnd, change.Replacement+after
+			default:
+				change.FixStart, change.Fix = nextStart, change.Replacement+after
+			}
+			change.FixEnd = change.FixStart
+		default:
+			gap := correction[prevEndC:nextStartC]
+			switch {
+			case gap != "" && i < len(a):
+				change.FixStart, change.FixEnd = change.Start, nextStart
+			case gap != "":
+				change.FixStart, change.FixEnd = prevEnd, change.End
+			default:
+				change.FixStart, change.FixEnd = prevEnd, nextStart
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// Message describes the change in a few words, e.g. "go → went".
+func (c Change) Message() string {
+	switch {
+	case c.Original == "":
+		return "insert \"" + c.Replacement + "\""
+	case c.Replacement == "":
+		return "remove \"" + c.Original + "\""
+	default:
+		return c.Original + " → " + c.Replacement
+	}
+}
@@ -0,0 +1,151 @@
+package lsp
+
+import (
+	"testing"
+)
+
+type DiffTest struct {
+	Original   string
+	Correction string
+	Expected   []Change
+}
+
+func TestDiffWords(t *testing.T) {
+	tests := []DiffTest{
+		{
+			Original:   "She go to the store yesterday",
+			Correction: "She went to the store yesterday.",
+			Expected: []Change{
+				{Start: 4, End: 6, Original: "go", Replacement: "went", FixStart: 4, FixEnd: 6, Fix: "went"},
+				{Start: 29, End: 29, Original: "", Replacement: ".", FixStart: 29, FixEnd: 29, Fix: "."},
+			},
+		},
+		{
+			Original:   "I went store",
+			Correction: "I went to the store",
+			Expected: []Change{
+				{Start: 7, End: 7, Original: "", Replacement: "to the", FixStart: 7, FixEnd: 7, Fix: "to the "},
+			},
+		},
+		{
+			Original:   "It is is fine",
+			Correction: "It is fine",
+			Expected: []Change{
+				{Start: 6, End: 8, Original: "is", Replacement: "", FixStart: 6, FixEnd: 9, Fix: ""},
+			},
+		},
+		{
+			Original:   "This is fine",
+			Correction: "This is fine",
+			Expected:   []Change{},
+		},
+	}
+
+	for _, test := range tests {
+		result := diffWords(test.Original, test.Correction)
+		if len(result) != len(test.Expected) {
+			t.Errorf("Expected %d changes, got %d: %v", len(test.Expected), len(result), result)
+			continue
+		}
+
+		for i, expected := range test.Expected {
+			if result[i] != expected {
+				t.Errorf("Expected %v, got %v", expected, result[i])
+			}
+		}
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	tests := []struct {
+		Original   string
+		Correction string
+	}{
+		{"She go to the store yesterday", "She went to the store yesterday."},
+		{"I went store", "I went to the store"},
+		{"It is is fine", "It is fine"},
+		{"store is closed", "The store is closed"},
+		{"Its fine really", "It's fine, really"},
+		{"Hello world! Bye", "Hello world! Bye now."},
+	}
+
+	for _, test := range tests {
+		result := test.Original
+		changes := diffWords(test.Original, test.Correction)
+		for i := len(changes) - 1; i >= 0; i-- {
+			change := changes[i]
+			result = result[:change.FixStart] + change.Fix + result[change.FixEnd:]
+		}
+		if result != test.Correction {
+			t.Errorf("Expected %q, got %q", test.Correction, result)
+		}
+	}
+}
+
+func TestConvertCheckToDiagnostics(t *testing.T) {
+	text := "Hello, world! She go to the\nstore yesterday."
+	sentence := parse(text)[1]
+
+	check := SentenceCheck{
+		Range:       sentence.Range,
+		HasError:    true,
+		Correction:  "She went to the shop yesterday.",
+		Explanation: "Use the past tense.",
+		Category:    CategoryStyle,
+		Rule:        "verb-tense",
+	}
+
+	diagnostics := ConvertCheckToDiagnostics(DiagnosticConfig{Severity: map[string]string{"style": "hint"}}, "file:///test.md", sentence, check)
+	expected := []Diagnostic{
+		{Range: Range{Position{0, 18}, Position{0, 20}}, Message: "go → went\n\nUse the past tense."},
+		{Range: Range{Position{1, 0}, Position{1, 5}}, Message: "store → shop\n\nUse the past tense."},
+	}
+
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diagnostics), diagnostics)
+	}
+
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Range != expected[i].Range || diagnostic.Message != expected[i].Message {
+			t.Errorf("Expected %v, got %v", expected[i], diagnostic)
+		}
+		if diagnostic.Severity != DiagnosticSeverityHint {
+			t.Errorf("Expected severity %d, got %d", DiagnosticSeverityHint, diagnostic.Severity)
+		}
+		if diagnostic.Code != "style/verb-tense" || diagnostic.Source != "jalsa" {
+			t.Errorf("Expected code style/verb-tense from jalsa, got %s from %s", diagnostic.Code, diagnostic.Source)
+		}
+		if diagnostic.CodeDescription.Href != "https://github.com/vramana/jalsa/blob/main/docs/rules.md#style" {
+			t.Errorf("Unexpected code description %s", diagnostic.CodeDescription.Href)
+		}
+		if diagnostic.Data == nil || diagnostic.Data.Fix == nil || diagnostic.Data.Fix.Range != expected[i].Range {
+			t.Errorf("Expected a fix at %v, got %v", expected[i].Range, diagnostic.Data)
+		}
+	}
+
+	if sentence.Source != "She go to the\nstore yesterday." {
+		t.Errorf("Unexpected source %q", sentence.Source)
+	}
+}
+
+func TestCodeAction(t *testing.T) {
+	server := newTestServer()
+	fix := &TextEdit{Range: Range{Position{0, 4}, Position{0, 6}}, NewText: "went"}
+	request := &CodeActionRequest{Params: CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///test.md"},
+		Context: CodeActionContext{Diagnostics: []Diagnostic{
+			{Source: "jalsa", Data: &DiagnosticData{Title: "go → went", Fix: fix}},
+			{Source: "jalsa"},
+			{Source: "other", Data: &DiagnosticData{Title: "go → went", Fix: fix}},
+		}},
+	}}
+
+	actions := server.CodeAction(request).Result
+	if len(actions) != 1 {
+		t.Fatalf("Expected 1 code action, got %d", len(actions))
+	}
+	edits := actions[0].Edit.Changes["file:///test.md"]
+	if actions[0].Kind != CodeActionKindQuickFix || len(edits) != 1 || edits[0] != *fix {
+		t.Errorf("Unexpected code action %v", actions[0])
+	}
+}
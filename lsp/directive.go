@@ -0,0 +1,202 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Directives are HTML comments that turn checking off and on again:
+//
+//	<!-- jalsa-disable -->             until the next jalsa-enable
+//	<!-- jalsa-enable -->
+//	<!-- jalsa-disable-next-line -->   only the sentences on the next line
+//	<!-- jalsa-disable style -->       only some categories
+var directiveRegex = regexp.MustCompile(`^<!--\s*jalsa-(disable-next-line|disable|enable)((?:[\s,]+[a-z]+)*)\s*-->$`)
+
+const (
+	directiveDisable         = "disable"
+	directiveEnable          = "enable"
+	directiveDisableNextLine = "disable-next-line"
+)
+
+type directive struct {
+	kind string
+	// line is the last line of the comment
+	line int
+	// categories is empty when the directive applies to all of them
+	categories []string
+}
+
+// parseDirective parses the text of an HTML block.
+func parseDirective(text string) (directive, bool) {
+	match := directiveRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return directive{}, false
+	}
+
+	categories := strings.FieldsFunc(match[2], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	return directive{kind: match[1], categories: categories}, true
+}
+
+// markdownDirectives returns the directives of a parsed markdown document in
+// document order.
+func markdownDirectives(doc *document, root *block) []directive {
+	directives := []directive{}
+
+	walkBlocks(root, func(b *block) {
+		if b.kind != blockHTML || len(b.lines) == 0 {
+			return
+		}
+
+		lines := []string{}
+		for _, line := range b.lines {
+			lines = append(lines, doc.text[line.start:line.end])
+		}
+		if d, ok := parseDirective(strings.Join(lines, "\n")); ok {
+			d.line = doc.position(b.lines[len(b.lines)-1].start).Line
+			directives = append(directives, d)
+		}
+	})
+
+	return directives
+}
+
+// applyDirectives records the categories disabled for every sentence and
+// drops the sentences that have all of them disabled.
+func applyDirectives(sentences []Sentence, directives []directive) []Sentence {
+	if len(directives) == 0 {
+		return sentences
+	}
+
+	result := []Sentence{}
+	for _, sentence := range sentences {
+		disabled := disabledCategories(directives, sentence.Range)
+
+		sentence.disabled = nil
+		for _, category := range Categories {
+			if disabled[category] {
+				sentence.disabled = append(sentence.disabled, category)
+			}
+		}
+		if len(sentence.disabled) < len(Categories) {
+			result = append(result, sentence)
+		}
+	}
+
+	return result
+}
+
+// disabledCategories returns the categories that directives disable for the
+// text in r.
+func disabledCategories(directives []directive, r Range) map[string]bool {
+	disabled := map[string]bool{}
+	set := func(categories []string, value bool) {
+		if len(categories) == 0 {
+			categories = append([]string{CategoryAccessibility}, Categories...)
+		}
+		for _, category := range categories {
+			disabled[category] = value
+		}
+	}
+
+	for _, d := range directives {
+		switch {
+		case d.kind == directiveDisableNextLine:
+			if d.line+1 >= r.Start.Line && d.line+1 <= r.End.Line {
+				set(d.categories, true)
+			}
+		case d.line >= r.Start.Line:
+		case d.kind == directiveDisable:
+			set(d.categories, true)
+		case d.kind == directiveEnable:
+			set(d.categories, false)
+		}
+	}
+
+	return disabled
+}
+
+// isDisabled reports whether a directive turned off a category for the
+// sentence.
+func (s Sentence) isDisabled(category string) bool {
+	for _, disabled := range s.disabled {
+		if disabled == category {
+			return true
+		}
+	}
+	return false
+}
+
+// disableActions offers to silence a diagnostic, for its category or for all
+// of them. A jalsa-disable-next-line directive goes above the paragraph when
+// the sentence starts on its first line, otherwise the paragraph is wrapped
+// in jalsa-disable and jalsa-enable.
+func disableActions(uri string, text string, diagnostic Diagnostic, exclusions []Exclusion) []CodeAction {
+	doc := newDocument(text)
+	line := diagnostic.Range.Start.Line
+	if len(diagnostic.RelatedInformation) > 0 {
+		line = diagnostic.RelatedInformation[0].Location.Range.Start.Line
+	}
+
+	var paragraph *block
+	walkBlocks(parseMarkdown(doc.text, frontMatterEnd(doc.text), exclusions), func(b *block) {
+		if (b.kind == blockParagraph || b.kind == blockHeading || b.kind == blockTable) && b.startLine <= line && line <= b.endLine {
+			paragraph = b
+		}
+	})
+	if paragraph == nil {
+		return nil
+	}
+
+	// Keep the directives inside the blockquote or list item of the paragraph
+	lineSpan := doc.lineSpan(paragraph.startLine)
+	prefix := doc.text[lineSpan.start:lineSpan.end]
+	prefix = prefix[:len(prefix)-len(strings.TrimLeft(prefix, " \t>"))]
+	if strings.TrimSpace(prefix) != "" && !strings.HasSuffix(prefix, " ") {
+		prefix += " "
+	}
+
+	type option struct{ title, categories string }
+	options := []option{}
+	if category, _, _ := strings.Cut(diagnostic.Code, "/"); category != "" {
+		options = append(options, option{"Disable jalsa " + category + " checks", " " + category})
+	}
+	options = append(options, option{"Disable jalsa", ""})
+
+	before := Position{Line: paragraph.startLine, Character: 0}
+	after := Position{Line: paragraph.endLine + 1, Character: 0}
+	afterPrefix := prefix
+	if paragraph.endLine+1 >= len(doc.lineStarts) {
+		end := doc.lineSpan(paragraph.endLine).end
+		after = doc.position(end)
+		afterPrefix = "\n" + prefix
+	}
+
+	actions := []CodeAction{}
+	for _, option := range options {
+		comment := func(kind string) string {
+			return "<!-- jalsa-" + kind + option.categories + " -->"
+		}
+
+		title := option.title + " for this line"
+		edits := []TextEdit{{Range: Range{before, before}, NewText: prefix + comment(directiveDisableNextLine) + "\n"}}
+		if line != paragraph.startLine {
+			title = option.title + " for this paragraph"
+			edits = []TextEdit{
+				{Range: Range{before, before}, NewText: prefix + comment(directiveDisable) + "\n"},
+				{Range: Range{after, after}, NewText: afterPrefix + comment(directiveEnable) + "\n"},
+			}
+		}
+
+		actions = append(actions, CodeAction{
+			Title:       title,
+			Kind:        CodeActionKindQuickFix,
+			Diagnostics: []Diagnostic{diagnostic},
+			Edit:        &WorkspaceEdit{Changes: map[string][]TextEdit{uri: edits}},
+		})
+	}
+
+	return actions
+}
@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDirectives(t *testing.T) {
+	text := `First sentence.
+
+<!-- jalsa-disable -->
+Quoted lyrics.
+<!-- jalsa-enable -->
+
+<!-- jalsa-disable-next-line -->
+Dialect here. And here.
+Not on the next line.
+
+<!-- jalsa-disable style, clarity -->
+Only grammar is checked.
+<!-- jalsa-enable style -->
+Clarity is still off.
+`
+	sentences := parse(text)
+
+	expected := []struct {
+		Text     string
+		Disabled []string
+	}{
+		{"First sentence.", nil},
+		{"Not on the next line.", nil},
+		{"Only grammar is checked.", []string{CategoryStyle, CategoryClarity}},
+		{"Clarity is still off.", []string{CategoryClarity}},
+	}
+
+	if len(sentences) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(sentences), sentences)
+	}
+	for i, sentence := range sentences {
+		if sentence.Text != expected[i].Text {
+			t.Errorf("Expected %s, got %s", expected[i].Text, sentence.Text)
+		}
+		if strings.Join(sentence.disabled, ",") != strings.Join(expected[i].Disabled, ",") {
+			t.Errorf("Expected %v disabled, got %v", expected[i].Disabled, sentence.disabled)
+		}
+	}
+
+	check := SentenceCheck{HasError: true, Correction: "Only grammar is check.", Category: CategoryStyle}
+	if diagnostics := ConvertCheckToDiagnostics(DiagnosticConfig{}, "file:///test.md", sentences[2], check); len(diagnostics) != 0 {
+		t.Errorf("Expected disabled diagnostics to be dropped, got %v", diagnostics)
+	}
+}
+
+func TestDisableActions(t *testing.T) {
+	text := "> She go home. It were\n> late.\n"
+	tests := []struct {
+		Line     int
+		Expected []TextEdit
+	}{
+		{0, []TextEdit{
+			{Range: Range{Position{0, 0}, Position{0, 0}}, NewText: "> <!-- jalsa-disable-next-line grammar -->\n"},
+		}},
+		{1, []TextEdit{
+			{Range: Range{Position{0, 0}, Position{0, 0}}, NewText: "> <!-- jalsa-disable grammar -->\n"},
+			{Range: Range{Position{2, 0}, Position{2, 0}}, NewText: "> <!-- jalsa-enable grammar -->\n"},
+		}},
+	}
+
+	for _, test := range tests {
+		diagnostic := Diagnostic{
+			Range:  Range{Position{test.Line, 2}, Position{test.Line, 4}},
+			Code:   "grammar/subject-verb-agreement",
+			Source: "jalsa",
+			RelatedInformation: []DiagnosticRelatedInformation{{
+				Location: Location{Range: Range{Position{test.Line, 2}, Position{test.Line, 5}}},
+			}},
+		}
+
+		actions := disableActions("file:///test.md", text, diagnostic, exclusions)
+		if len(actions) != 2 {
+			t.Fatalf("Expected 2 code actions, got %d", len(actions))
+		}
+		edits := actions[0].Edit.Changes["file:///test.md"]
+		if len(edits) != len(test.Expected) {
+			t.Fatalf("Expected %d edits, got %v", len(test.Expected), edits)
+		}
+		for i, edit := range edits {
+			if edit != test.Expected[i] {
+				t.Errorf("Expected %v, got %v", test.Expected[i], edit)
+			}
+		}
+	}
+}
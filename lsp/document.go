@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// document converts byte offsets into line and character positions.
+type document struct {
+	text       string
+	lineStarts []int
+	segmenter  Segmenter
+	exclusions []Exclusion
+	// messageFuncs are the functions whose string arguments are checked in
+	// Go files.
+	messageFuncs []string
+	// language is told to the model when it isn't the default
+	language string
+}
+
+// setLanguage splits the sentences of the document with the segmenter of
+// language and checks them in language.
+func (d *document) setLanguage(language string) {
+	d.segmenter = segmenterFor(language)
+	d.language = language
+}
+
+func newDocument(text string) *document {
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &document{text: text, lineStarts: lineStarts, segmenter: segmenterFor(defaultLanguage)}
+}
+
+func (d *document) position(offset int) Position {
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	return Position{Line: line, Character: offset - d.lineStarts[line]}
+}
+
+// lineSpan returns the offsets of a line without its line break.
+func (d *document) lineSpan(line int) span {
+	start := d.lineStarts[line]
+	end := len(d.text)
+	if line+1 < len(d.lineStarts) {
+		end = d.lineStarts[line+1] - 1
+	}
+	if end > start && d.text[end-1] == '\r' {
+		end--
+	}
+	return span{start, end}
+}
+
+// segment is a run of prose together with the document offset of every byte
+// of its text.
+type segment struct {
+	text    string
+	offsets []int
+
+	// language is set when the text isn't in the language of the document,
+	// like the translations of a .po file
+	language string
+}
+
+// add appends the document text between start and end.
+func (s *segment) add(doc *document, start, end int) {
+	s.text += doc.text[start:end]
+	for i := start; i < end; i++ {
+		s.offsets = append(s.offsets, i)
+	}
+}
+
+// join appends text that isn't in the document, like the space between two
+// lines of a paragraph, and maps all of it to offset.
+func (s *segment) join(text string, offset int) {
+	s.text += text
+	for i := 0; i < len(text); i++ {
+		s.offsets = append(s.offsets, offset)
+	}
+}
+
+// addLines appends lines of text, joined by single spaces.
+func (s *segment) addLines(doc *document, lines []span) {
+	for i, line := range lines {
+		text := strings.TrimRight(doc.text[line.start:line.end], " \t")
+		if i > 0 && len(s.text) > 0 {
+			s.join(" ", s.offsets[len(s.offsets)-1]+1)
+		}
+		s.add(doc, line.start, line.start+len(text))
+	}
+}
+
+// sentence turns text[start:end] into a Sentence.
+func (d *document) sentence(s segment, start, end int) Sentence {
+	return Sentence{
+		Text:   s.text[start:end],
+		Source: d.text[s.offsets[start] : s.offsets[end-1]+1],
+		Range: Range{
+			Start: d.position(s.offsets[start]),
+			End:   d.position(s.offsets[end-1] + 1),
+		},
+		doc:     d,
+		offsets: s.offsets[start:end],
+	}
+}
+
+// normalizeSpace collapses every run of whitespace into a single space.
+func normalizeSpace(s segment) segment {
+	result := segment{}
+	for i := 0; i < len(s.text); {
+		r, size := utf8.DecodeRuneInString(s.text[i:])
+		if !unicode.IsSpace(r) {
+			result.append(s.slice(i, i+size))
+			i += size
+			continue
+		}
+
+		result.join(" ", s.offsets[i])
+		for i < len(s.text) {
+			r, size := utf8.DecodeRuneInString(s.text[i:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			i += size
+		}
+	}
+	return result
+}
+
+// sentences splits a segment into sentences with the document's segmenter,
+// or the one of the segment's language. Their text is normalized to single
+// spaces and keeps the punctuation ending the sentence.
+func (d *document) sentences(s segment) []Sentence {
+	language := d.language
+	segmenter := d.segmenter
+	if s.language != "" {
+		language = s.language
+		segmenter = segmenterFor(language)
+	}
+	if language == defaultLanguage {
+		language = ""
+	}
+	s = normalizeSpace(s)
+	result := []Sentence{}
+
+	for _, sentence := range segmenter.Segment(s.text) {
+		result = append(result, d.sentence(s, sentence.start, sentence.end))
+		result[len(result)-1].language = language
+	}
+
+	return result
+}
@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"log"
+	"regexp"
+	"sort"
+)
+
+// Exclusion recognizes markup that isn't prose, like math or the tags of
+// static site generators.
+type Exclusion struct {
+	Name string
+
+	// Start matches the first line of a block, and End the line that closes
+	// it. A block without End is a single line.
+	Start *regexp.Regexp
+	End   *regexp.Regexp
+	// Container blocks only exclude the line matched by Start, their body,
+	// indented by four spaces, is checked like the rest of the document.
+	Container bool
+
+	// Inline matches spans inside prose, which become a placeholder.
+	Inline *regexp.Regexp
+}
+
+var exclusions = []Exclusion{}
+
+// RegisterExclusion makes a recognizer available. Documents are parsed with
+// all of them unless the config disables some.
+func RegisterExclusion(exclusion Exclusion) {
+	exclusions = append(exclusions, exclusion)
+}
+
+func init() {
+	RegisterExclusion(Exclusion{
+		Name:   "math",
+		Start:  regexp.MustCompile(`^\$\$`),
+		End:    regexp.MustCompile(`\$\$[ \t]*$`),
+		Inline: regexp.MustCompile(`\$\$.+?\$\$|\\\(.+?\\\)`),
+	})
+	RegisterExclusion(Exclusion{
+		Name:  "math",
+		Start: regexp.MustCompile(`^\\\[`),
+		End:   regexp.MustCompile(`\\\][ \t]*$`),
+	})
+	RegisterExclusion(Exclusion{
+		Name:      "footnotes",
+		Start:     regexp.MustCompile(`^\[\^[^\]\s]+\]:[ \t]*`),
+		Container: true,
+		Inline:    regexp.MustCompile(`\[\^[^\]\s]+\]`),
+	})
+	RegisterExclusion(Exclusion{
+		Name:      "admonitions",
+		Start:     regexp.MustCompile(`^(?:!!!|\?\?\?\+?)[ \t]+[\w-]+(?:[ \t]+"[^"]*")?[ \t]*$`),
+		Container: true,
+	})
+	RegisterExclusion(Exclusion{
+		Name:  "shortcodes",
+		Start: regexp.MustCompile(`^\{\{[<%][ \t]*highlight\b`),
+		End:   regexp.MustCompile(`\{\{[<%][ \t]*/highlight[ \t]*[%>]\}\}`),
+	})
+	RegisterExclusion(Exclusion{
+		Name:   "shortcodes",
+		Start:  regexp.MustCompile(`^\{\{[<%].*[%>]\}\}[ \t]*$`),
+		Inline: regexp.MustCompile(`\{\{[<%].*?[%>]\}\}`),
+	})
+	RegisterExclusion(Exclusion{
+		Name:  "liquid",
+		Start: regexp.MustCompile(`^\{%-?[ \t]*(?:highlight|raw|comment)\b`),
+		End:   regexp.MustCompile(`\{%-?[ \t]*end(?:highlight|raw|comment)[ \t]*-?%\}`),
+	})
+	RegisterExclusion(Exclusion{
+		Name:   "liquid",
+		Start:  regexp.MustCompile(`^\{%.*%\}[ \t]*$`),
+		Inline: regexp.MustCompile(`\{%.*?%\}|\{\{.*?\}\}`),
+	})
+}
+
+// ExclusionConfig adjusts the recognizers used to skip markup. Disable lists
+// built-in recognizers by name, e.g. "math" or "liquid". Inline and Blocks add
+// regular expressions of their own.
+type ExclusionConfig struct {
+	Disable []string       `json:"disable"`
+	Inline  []string       `json:"inline"`
+	Blocks  []BlockPattern `json:"blocks"`
+}
+
+// BlockPattern matches the first and last lines of a block. Without End the
+// block is a single line.
+type BlockPattern struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Exclusions returns the recognizers enabled by the config. Patterns that
+// don't compile are logged and skipped.
+func (c ExclusionConfig) Exclusions(logger *log.Logger) []Exclusion {
+	disabled := map[string]bool{}
+	for _, name := range c.Disable {
+		disabled[name] = true
+	}
+
+	result := []Exclusion{}
+	for _, exclusion := range exclusions {
+		if !disabled[exclusion.Name] {
+			result = append(result, exclusion)
+		}
+	}
+
+	for _, pattern := range c.Inline {
+		inline, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Printf("Invalid inline exclusion %q: %s", pattern, err)
+			continue
+		}
+		result = append(result, Exclusion{Name: "config", Inline: inline})
+	}
+	for _, pattern := range c.Blocks {
+		start, err := regexp.Compile(pattern.Start)
+		if err != nil {
+			logger.Printf("Invalid block exclusion %q: %s", pattern.Start, err)
+			continue
+		}
+		var end *regexp.Regexp
+		if pattern.End != "" {
+			if end, err = regexp.Compile(pattern.End); err != nil {
+				logger.Printf("Invalid block exclusion %q: %s", pattern.End, err)
+				continue
+			}
+		}
+		result = append(result, Exclusion{Name: "config", Start: start, End: end})
+	}
+
+	return result
+}
+
+// excludeInline replaces the spans matched by inline recognizers with a
+// placeholder.
+func excludeInline(s segment, exclusions []Exclusion) segment {
+	matches := [][]int{}
+	for _, exclusion := range exclusions {
+		if exclusion.Inline != nil {
+			matches = append(matches, exclusion.Inline.FindAllStringIndex(s.text, -1)...)
+		}
+	}
+	if len(matches) == 0 {
+		return s
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+
+	result := segment{}
+	i := 0
+	for _, match := range matches {
+		if match[0] < i || match[1] == match[0] {
+			continue
+		}
+		result.append(s.slice(i, match[0]))
+		result.replace(codePlaceholder, s.offsets[match[0]], s.offsets[match[1]-1]+1)
+		i = match[1]
+	}
+	result.append(s.slice(i, len(s.text)))
+	return result
+}
@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"io"
+	"log"
+	"testing"
+)
+
+func TestExclusions(t *testing.T) {
+	text := `Euler wrote \(e^{i\pi} + 1 = 0\) once.
+
+$$
+x = \frac{a}{b}
+$$
+
+{{< figure src="a.png" >}}
+{% include note.html %}
+Hello {{ page.title }} and {{< ref "b" >}}.
+
+A claim.[^1]
+
+[^1]: The footnote body
+    continues here.
+
+!!! note "Not checked"
+    The admonition body.
+
+{{< highlight go >}}
+func main() {}
+{{< /highlight >}}
+`
+	expected := []Sentence{
+		{Text: "Euler wrote ⟦code⟧ once.", Range: Range{Position{0, 0}, Position{0, 38}}},
+		{Text: "Hello ⟦code⟧ and ⟦code⟧.", Range: Range{Position{8, 0}, Position{8, 43}}},
+		{Text: "A claim.⟦code⟧", Range: Range{Position{10, 0}, Position{10, 12}}},
+		{Text: "The footnote body continues here.", Range: Range{Position{12, 6}, Position{13, 19}}},
+		{Text: "The admonition body.", Range: Range{Position{16, 4}, Position{16, 24}}},
+	}
+
+	result := parse(text)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i, sentence := range expected {
+		testSentence(t, result[i], sentence)
+	}
+
+	config := ExclusionConfig{Disable: []string{"math"}, Inline: []string{`\bTODO\b`, `(`}}
+	options := parseOptions{language: defaultLanguage, exclusions: config.Exclusions(log.New(io.Discard, "", 0))}
+	result = parseDocument("Euler wrote \\(e\\) once.\n\nTODO: a claim.\n", options)
+	if len(result) != 2 || result[0].Text != "Euler wrote (e) once." || result[1].Text != "⟦code⟧: a claim." {
+		t.Errorf("Unexpected sentences %v", result)
+	}
+}
@@ -0,0 +1,266 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// proseFields are the front matter keys whose values are checked like the
+// rest of the document.
+var proseFields = []string{"title", "description", "summary"}
+
+// frontMatter holds what the front matter at the top of a document says
+// about checking it.
+type frontMatter struct {
+	// end is the offset just after the closing marker, 0 without front matter
+	end int
+
+	// prose holds the values of the prose fields
+	prose []segment
+
+	// language comes from the "lang" key
+	language string
+	// disabled is set by "jalsa: false"
+	disabled bool
+	// ignore lists the categories in "jalsa_ignore"
+	ignore []string
+}
+
+// frontMatterValue is a scalar value, with the document offsets of its
+// text, or a list.
+type frontMatterValue struct {
+	text  segment
+	items []string
+}
+
+var (
+	yamlKeyRegex = regexp.MustCompile(`^([A-Za-z_][\w-]*)[ \t]*:(?:[ \t]+|$)`)
+	tomlKeyRegex = regexp.MustCompile(`^([A-Za-z_][\w-]*)[ \t]*=[ \t]*`)
+)
+
+// parseFrontMatter parses YAML front matter between "---" lines or TOML
+// front matter between "+++" lines. Only top level keys are read.
+func parseFrontMatter(doc *document) frontMatter {
+	fm := frontMatter{end: frontMatterEnd(doc.text)}
+	if fm.end == 0 {
+		return fm
+	}
+
+	// The lines between the markers
+	first := 1
+	last := doc.position(fm.end-1).Line - 1
+	if doc.text[fm.end-1] != '\n' {
+		last = len(doc.lineStarts) - 2
+	}
+
+	var values map[string]frontMatterValue
+	if doc.text[0] == '+' {
+		values = parseTOML(doc, first, last)
+	} else {
+		values = parseYAML(doc, first, last)
+	}
+
+	for _, key := range proseFields {
+		if value, ok := values[key]; ok && value.text.text != "" {
+			fm.prose = append(fm.prose, stripInline(value.text))
+		}
+	}
+	if value, ok := values["lang"]; ok {
+		fm.language = value.text.text
+	}
+	if value, ok := values["jalsa"]; ok && value.text.text == "false" {
+		fm.disabled = true
+	}
+	if value, ok := values["jalsa_ignore"]; ok {
+		fm.ignore = value.items
+		if value.text.text != "" {
+			fm.ignore = []string{value.text.text}
+		}
+	}
+
+	return fm
+}
+
+func parseYAML(doc *document, first, last int) map[string]frontMatterValue {
+	values := map[string]frontMatterValue{}
+
+	for line := first; line <= last; {
+		s := doc.lineSpan(line)
+		text := doc.text[s.start:s.end]
+		match := yamlKeyRegex.FindStringSubmatch(text)
+		line++
+		if match == nil {
+			continue
+		}
+
+		// Nested lines are indented, or list items
+		nested := []span{}
+		for ; line <= last; line++ {
+			next := doc.lineSpan(line)
+			content := doc.text[next.start:next.end]
+			if strings.TrimSpace(content) != "" && content[0] != ' ' && content[0] != '\t' && !strings.HasPrefix(content, "- ") {
+				break
+			}
+			nested = append(nested, next)
+		}
+
+		start := s.start + len(match[0])
+		value := strings.TrimRight(stripYAMLComment(doc.text[start:s.end]), " \t")
+		values[match[1]] = yamlValue(doc, start, start+len(value), nested)
+	}
+
+	return values
+}
+
+// stripYAMLComment removes a comment, which starts with " #", from a plain
+// value.
+func stripYAMLComment(value string) string {
+	if strings.HasPrefix(value, "#") {
+		return ""
+	}
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		return value
+	}
+	if i := strings.Index(value, " #"); i != -1 {
+		return value[:i]
+	}
+	return value
+}
+
+func yamlValue(doc *document, start, end int, nested []span) frontMatterValue {
+	value := doc.text[start:end]
+	trimmed := func(lines []span) []span {
+		result := []span{}
+		for _, line := range lines {
+			text := doc.text[line.start:line.end]
+			line.start += len(text) - len(strings.TrimLeft(text, " \t"))
+			if line.start < line.end {
+				result = append(result, line)
+			}
+		}
+		return result
+	}
+
+	switch {
+	case value == "":
+		items := []string{}
+		for _, line := range trimmed(nested) {
+			item, ok := strings.CutPrefix(doc.text[line.start:line.end], "- ")
+			if ok {
+				items = append(items, unquote(strings.TrimSpace(item)))
+			}
+		}
+		return frontMatterValue{items: items}
+
+	case value[0] == '"' || value[0] == '\'':
+		return frontMatterValue{text: quotedValue(doc, start, end)}
+
+	case value[0] == '[':
+		return frontMatterValue{items: flowItems(value)}
+
+	case value[0] == '|' || value[0] == '>':
+		s := segment{}
+		s.addLines(doc, trimmed(nested))
+		return frontMatterValue{text: s}
+
+	default:
+		// Plain scalars continue on indented lines
+		s := segment{}
+		s.addLines(doc, append([]span{{start, end}}, trimmed(nested)...))
+		return frontMatterValue{text: s}
+	}
+}
+
+func parseTOML(doc *document, first, last int) map[string]frontMatterValue {
+	values := map[string]frontMatterValue{}
+
+	for line := first; line <= last; line++ {
+		s := doc.lineSpan(line)
+		text := doc.text[s.start:s.end]
+		// Keys after the first table belong to the table
+		if strings.HasPrefix(text, "[") {
+			break
+		}
+		match := tomlKeyRegex.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		start := s.start + len(match[0])
+		value := strings.TrimRight(doc.text[start:s.end], " \t")
+		switch {
+		case strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''"):
+			// Multi-line strings end with the same quotes
+			quotes := value[:3]
+			lines := []span{{start + 3, start + len(value)}}
+			for !strings.HasSuffix(doc.text[lines[len(lines)-1].start:lines[len(lines)-1].end], quotes) && line < last {
+				line++
+				lines = append(lines, doc.lineSpan(line))
+			}
+			lines[len(lines)-1].end = max(lines[len(lines)-1].start, lines[len(lines)-1].end-3)
+			v := segment{}
+			v.addLines(doc, lines)
+			values[match[1]] = frontMatterValue{text: v}
+		case value != "" && (value[0] == '"' || value[0] == '\''):
+			values[match[1]] = frontMatterValue{text: quotedValue(doc, start, start+len(value))}
+		case strings.HasPrefix(value, "["):
+			values[match[1]] = frontMatterValue{items: flowItems(value)}
+		default:
+			v := segment{}
+			v.add(doc, start, start+len(value))
+			values[match[1]] = frontMatterValue{text: v}
+		}
+	}
+
+	return values
+}
+
+// quotedValue returns the text of a quoted string at doc.text[start:end],
+// with escapes decoded.
+func quotedValue(doc *document, start, end int) segment {
+	s := segment{}
+	quote := doc.text[start]
+	for i := start + 1; i < end; i++ {
+		c := doc.text[i]
+		switch {
+		case c == quote && quote == '\'' && i+1 < end && doc.text[i+1] == '\'':
+			s.replace("'", i, i+2)
+			i++
+		case c == quote:
+			return s
+		case c == '\\' && quote == '"' && i+1 < end:
+			escaped := doc.text[i+1]
+			switch escaped {
+			case 'n', 't':
+				s.replace(" ", i, i+2)
+			default:
+				s.replace(string(escaped), i, i+2)
+			}
+			i++
+		default:
+			s.add(doc, i, i+1)
+		}
+	}
+	return s
+}
+
+// flowItems returns the items of a list like [style, "clarity"].
+func flowItems(value string) []string {
+	value = strings.TrimPrefix(value, "[")
+	value, _, _ = strings.Cut(value, "]")
+
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
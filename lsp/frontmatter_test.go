@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFrontMatterProse(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected []Sentence
+	}{
+		{
+			Text: "---\ntitle: \"A \\\"quoted\\\" title\"\ndate: 2024-01-01\ndescription: >\n  Folded text\n  over lines.\nsummary: It's plain # a comment\ntags:\n- go\nparams:\n  title: Nested\n---\nBody.\n",
+			Expected: []Sentence{
+				{Text: "A \"quoted\" title", Range: Range{Position{1, 8}, Position{1, 26}}},
+				{Text: "Folded text over lines.", Range: Range{Position{4, 2}, Position{5, 13}}},
+				{Text: "It's plain", Range: Range{Position{6, 9}, Position{6, 19}}},
+				{Text: "Body.", Range: Range{Position{12, 0}, Position{12, 5}}},
+			},
+		},
+		{
+			Text: "+++\ntitle = 'Don''t panic'\ndescription = \"\"\"\nOn two\nlines.\"\"\"\n[params]\nsummary = \"Nested\"\n+++\n",
+			Expected: []Sentence{
+				{Text: "Don't panic", Range: Range{Position{1, 9}, Position{1, 21}}},
+				{Text: "On two lines.", Range: Range{Position{3, 0}, Position{4, 6}}},
+			},
+		},
+		{
+			Text: "Intro.\n\n---\ntitle: Not front matter\n---\n",
+			Expected: []Sentence{
+				{Text: "Intro.", Range: Range{Position{0, 0}, Position{0, 6}}},
+				{Text: "title: Not front matter", Range: Range{Position{3, 0}, Position{3, 23}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		result := parse(test.Text)
+		if len(result) != len(test.Expected) {
+			t.Errorf("Expected %d sentences, got %d: %v", len(test.Expected), len(result), result)
+			continue
+		}
+		for i, expected := range test.Expected {
+			testSentence(t, result[i], expected)
+		}
+	}
+}
+
+func TestFrontMatterSettings(t *testing.T) {
+	if result := parse("---\njalsa: false\ntitle: Skipped\n---\nAlso skipped.\n"); len(result) != 0 {
+		t.Errorf("Expected no sentences, got %v", result)
+	}
+
+	result := parse("---\njalsa_ignore: [style, \"clarity\"]\n---\nChecked.\n")
+	if len(result) != 1 || strings.Join(result[0].disabled, ",") != "style,clarity" {
+		t.Errorf("Expected style and clarity to be disabled, got %v", result)
+	}
+
+	// German abbreviations only apply with lang: de
+	text := "---\nlang: de\n---\nDas ist z.B. ein Satz. Noch einer.\n"
+	result = parseWithLanguage(text, "en")
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 sentences, got %d: %v", len(result), result)
+	}
+	// and the model is told the language
+	if result[0].language != "de" {
+		t.Errorf("Expected language de, got %q", result[0].language)
+	}
+	if result := parse("Checked.\n"); result[0].language != "" {
+		t.Errorf("Expected no language for the default, got %q", result[0].language)
+	}
+}
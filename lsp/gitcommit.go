@@ -0,0 +1,250 @@
+package lsp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerParser("gitcommit", commitSegments)
+}
+
+// CategoryCommit is reported by the checks of commit subjects, not by the
+// model.
+const CategoryCommit = "commit"
+
+// commitSubjectLength is the longest subject that fits in git log --oneline
+// and the subject lines of patch emails.
+const commitSubjectLength = 72
+
+var (
+	commitScissorsRegex = regexp.MustCompile(`^# -+ >8 -+$`)
+	commitTrailerRegex  = regexp.MustCompile(`^[A-Za-z][\w-]*:[ \t]+\S`)
+	commitPrefixRegex   = regexp.MustCompile(`^(?:(?:fixup|squash|amend)! )*(?:[\w-]+(?:\([^)]*\))?!?:[ \t]+)?`)
+	commitBulletRegex   = regexp.MustCompile(`^[ \t]*[-*][ \t]+`)
+)
+
+// commitVerbs are verbs that start commit subjects. Their other forms, like
+// "Added" or "Fixes", are reported with the imperative as a fix.
+var commitVerbs = []string{
+	"add", "allow", "avoid", "bump", "change", "check", "clean", "clarify", "correct", "create", "delete",
+	"deprecate", "disable", "document", "drop", "enable", "ensure", "extract", "fix", "handle",
+	"implement", "improve", "introduce", "make", "merge", "move", "optimize", "prevent", "refactor",
+	"release", "remove", "rename", "reorder", "replace", "restore", "revert", "rewrite", "run", "set",
+	"show", "simplify", "skip", "speed", "split", "stop", "support", "switch", "test", "tidy", "update",
+	"upgrade", "use", "validate", "write",
+}
+
+// commitIrregularVerbs are the forms of the commitVerbs that don't follow
+// the rules below.
+var commitIrregularVerbs = map[string][]string{
+	"make":    {"makes", "made", "making"},
+	"rewrite": {"rewrites", "rewrote", "rewritten", "rewriting"},
+	"run":     {"runs", "ran", "running"},
+	"set":     {"sets", "setting"},
+	"speed":   {"speeds", "sped", "speeding"},
+	"split":   {"splits", "splitting"},
+	"write":   {"writes", "wrote", "written", "writing"},
+}
+
+// commitVerbForms maps the other forms of commitVerbs to the imperative.
+var commitVerbForms = map[string]string{}
+
+func init() {
+	for _, verb := range commitVerbs {
+		if forms, ok := commitIrregularVerbs[verb]; ok {
+			for _, form := range forms {
+				commitVerbForms[form] = verb
+			}
+			continue
+		}
+
+		last := verb[len(verb)-1:]
+		forms := []string{verb + "s", verb + "ed", verb + "ing"}
+		switch {
+		case strings.HasSuffix(verb, "e"):
+			forms = []string{verb + "s", verb + "d", strings.TrimSuffix(verb, "e") + "ing"}
+		case strings.HasSuffix(verb, "y") && !strings.ContainsAny(verb[len(verb)-2:len(verb)-1], "aeiou"):
+			stem := strings.TrimSuffix(verb, "y")
+			forms = []string{stem + "ies", stem + "ied", verb + "ing"}
+		case strings.HasSuffix(verb, "sh") || strings.HasSuffix(verb, "ch") || strings.ContainsAny(last, "sxz"):
+			// Only sibilants take "es", like "switches", but "checks"
+			forms = []string{verb + "es", verb + "ed", verb + "ing"}
+		case len(verb) <= 4 && strings.ContainsAny(verb[len(verb)-2:len(verb)-1], "aeiou") &&
+			!strings.ContainsAny(verb[len(verb)-3:len(verb)-2], "aeiou") && !strings.ContainsAny(last, "wy"):
+			// Short verbs double their last consonant, like "dropped"
+			forms = []string{verb + "s", verb + last + "ed", verb + last + "ing"}
+		}
+		for _, form := range forms {
+			commitVerbForms[form] = verb
+		}
+	}
+}
+
+// commitMessage is the subject and the body paragraphs of a commit message,
+// as lines without comments, the scissors section and trailers.
+type commitMessage struct {
+	subject span
+	body    [][]span
+}
+
+func parseCommitMessage(doc *document) commitMessage {
+	message := commitMessage{subject: span{-1, -1}}
+	paragraphs := [][]span{}
+	paragraph := []span{}
+	for i := range doc.lineStarts {
+		line := doc.lineSpan(i)
+		text := doc.text[line.start:line.end]
+		if commitScissorsRegex.MatchString(text) {
+			break
+		}
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		line.end = line.start + len(strings.TrimRight(text, " \t"))
+		if line.start == line.end {
+			if len(paragraph) > 0 {
+				paragraphs = append(paragraphs, paragraph)
+				paragraph = []span{}
+			}
+			continue
+		}
+		if message.subject.start == -1 {
+			message.subject = line
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	if len(paragraph) > 0 {
+		paragraphs = append(paragraphs, paragraph)
+	}
+
+	// The last paragraph holds the trailers, like Signed-off-by
+	if n := len(paragraphs); n > 0 {
+		trailers := true
+		for _, line := range paragraphs[n-1] {
+			text := doc.text[line.start:line.end]
+			if !commitTrailerRegex.MatchString(text) && text[0] != ' ' && text[0] != '\t' {
+				trailers = false
+			}
+		}
+		if trailers {
+			paragraphs = paragraphs[:n-1]
+		}
+	}
+
+	message.body = paragraphs
+	return message
+}
+
+// subjectText returns the start of the subject after prefixes like "fixup! "
+// or "feat(parser): ", and whether the subject is written by git, like the
+// subjects of merges and reverts.
+func (m commitMessage) subjectText(doc *document) (int, bool) {
+	text := doc.text[m.subject.start:m.subject.end]
+	generated := strings.HasPrefix(text, "Merge ") || strings.HasPrefix(text, "Revert \"")
+	return m.subject.start + len(commitPrefixRegex.FindString(text)), generated
+}
+
+// commitSegments extracts the subject and the body paragraphs of a commit
+// message. Bullets of a list in the body are segments of their own.
+func commitSegments(doc *document) []segment {
+	message := parseCommitMessage(doc)
+	if message.subject.start == -1 {
+		return []segment{}
+	}
+
+	segments := []segment{}
+	start, _ := message.subjectText(doc)
+	subject := segment{}
+	subject.add(doc, start, message.subject.end)
+	if hasProse(subject.text) {
+		segments = append(segments, subject)
+	}
+
+	for _, paragraph := range message.body {
+		s := segment{}
+		for _, line := range paragraph {
+			text := doc.text[line.start:line.end]
+			if bullet := commitBulletRegex.FindString(text); bullet != "" {
+				if hasProse(s.text) {
+					segments = append(segments, s)
+				}
+				s = segment{}
+				line.start += len(bullet)
+			} else {
+				line.start += len(text) - len(strings.TrimLeft(text, " \t"))
+			}
+			if len(s.text) > 0 {
+				s.join(" ", line.start-1)
+			}
+			s.add(doc, line.start, line.end)
+		}
+		if hasProse(s.text) {
+			segments = append(segments, s)
+		}
+	}
+
+	return segments
+}
+
+// commitDiagnostics checks the subject of a commit message: its length, that
+// it starts with a verb in the imperative mood and that it doesn't end with a
+// period.
+func commitDiagnostics(config DiagnosticConfig, text string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	doc := newDocument(text)
+	commit := parseCommitMessage(doc)
+	if commit.subject.start == -1 {
+		return diagnostics
+	}
+	subject := doc.text[commit.subject.start:commit.subject.end]
+	start, generated := commit.subjectText(doc)
+
+	// Diagnostics with a title have a fix replacing their range
+	diagnostic := func(rule, message string, start, end int, title, replacement string) {
+		r := Range{Start: doc.position(start), End: doc.position(end)}
+		d := Diagnostic{
+			Range:           r,
+			Severity:        config.severity(CategoryCommit),
+			Code:            CategoryCommit + "/" + rule,
+			CodeDescription: config.codeDescription(CategoryCommit),
+			Source:          "jalsa",
+			Message:         message,
+		}
+		if title != "" {
+			d.Data = &DiagnosticData{Title: title, Fix: &TextEdit{Range: r, NewText: replacement}}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	if length := len([]rune(subject)); length > commitSubjectLength {
+		cut := commit.subject.start + len(string([]rune(subject)[:commitSubjectLength]))
+		diagnostic("subject-length", "Subject is "+strconv.Itoa(length)+" characters long. Keep it to "+strconv.Itoa(commitSubjectLength)+".",
+			cut, commit.subject.end, "", "")
+	}
+	if generated {
+		return diagnostics
+	}
+
+	word := doc.text[start:commit.subject.end]
+	if i := strings.IndexAny(word, " \t:,"); i != -1 {
+		word = word[:i]
+	}
+	if verb, ok := commitVerbForms[strings.ToLower(word)]; ok {
+		if word[:1] != strings.ToLower(word[:1]) {
+			verb = strings.ToUpper(verb[:1]) + verb[1:]
+		}
+		diagnostic("imperative-mood", "Use the imperative mood: \""+verb+"\" instead of \""+word+"\".",
+			start, start+len(word), word+" → "+verb, verb)
+	}
+
+	if strings.HasSuffix(subject, ".") && !strings.HasSuffix(subject, "..") {
+		end := commit.subject.end
+		diagnostic("subject-period", "Subject doesn't end with a period.", end-1, end, "remove \".\"", "")
+	}
+
+	return diagnostics
+}
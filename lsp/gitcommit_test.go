@@ -0,0 +1,98 @@
+package lsp
+
+import "testing"
+
+func TestCommitSegments(t *testing.T) {
+	text := "fix(parser): Handles empty lines\n\n" +
+		"The parser crashed on empty\nlines at the end.\n\n" +
+		"- First bullet.\n- Second bullet.\n\n" +
+		"Signed-off-by: Jane Doe <jane@example.com>\n" +
+		"# Please enter the commit message.\n" +
+		"# ------------------------ >8 ------------------------\n" +
+		"diff --git a/parser.go b/parser.go\n"
+
+	expected := []Sentence{
+		{Text: "Handles empty lines", Range: Range{Position{0, 13}, Position{0, 32}}},
+		{Text: "The parser crashed on empty lines at the end.", Range: Range{Position{2, 0}, Position{3, 17}}},
+		{Text: "First bullet.", Range: Range{Position{5, 2}, Position{5, 15}}},
+		{Text: "Second bullet.", Range: Range{Position{6, 2}, Position{6, 16}}},
+	}
+
+	result := parseSegments(text, parseOptions{language: defaultLanguage}, commitSegments)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i := range expected {
+		testSentence(t, result[i], expected[i])
+	}
+}
+
+func TestCommitDiagnostics(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected []Diagnostic
+	}{
+		{"Add a parser for commit messages\n", nil},
+		{"# Comment\nfeat: Added support for tabs.\n", []Diagnostic{
+			{Range: Range{Position{1, 6}, Position{1, 11}}, Code: "commit/imperative-mood",
+				Data: &DiagnosticData{Title: "Added → Add", Fix: &TextEdit{Range: Range{Position{1, 6}, Position{1, 11}}, NewText: "Add"}}},
+			{Range: Range{Position{1, 28}, Position{1, 29}}, Code: "commit/subject-period",
+				Data: &DiagnosticData{Title: "remove \".\"", Fix: &TextEdit{Range: Range{Position{1, 28}, Position{1, 29}}, NewText: ""}}},
+		}},
+		{"fixing the bug where the server drops the cached diagnostics of closed files\n", []Diagnostic{
+			{Range: Range{Position{0, 72}, Position{0, 76}}, Code: "commit/subject-length"},
+			{Range: Range{Position{0, 0}, Position{0, 6}}, Code: "commit/imperative-mood",
+				Data: &DiagnosticData{Title: "fixing → fix", Fix: &TextEdit{Range: Range{Position{0, 0}, Position{0, 6}}, NewText: "fix"}}},
+		}},
+		{"Merge branch 'main' into feature.\n", nil},
+		{"Checks the length of subjects\n", []Diagnostic{
+			{Range: Range{Position{0, 0}, Position{0, 6}}, Code: "commit/imperative-mood",
+				Data: &DiagnosticData{Title: "Checks → Check", Fix: &TextEdit{Range: Range{Position{0, 0}, Position{0, 6}}, NewText: "Check"}}},
+		}},
+		{"Switches the parser\n", []Diagnostic{
+			{Range: Range{Position{0, 0}, Position{0, 8}}, Code: "commit/imperative-mood",
+				Data: &DiagnosticData{Title: "Switches → Switch", Fix: &TextEdit{Range: Range{Position{0, 0}, Position{0, 8}}, NewText: "Switch"}}},
+		}},
+		{"Splitting the parser\n", []Diagnostic{
+			{Range: Range{Position{0, 0}, Position{0, 9}}, Code: "commit/imperative-mood",
+				Data: &DiagnosticData{Title: "Splitting → Split", Fix: &TextEdit{Range: Range{Position{0, 0}, Position{0, 9}}, NewText: "Split"}}},
+		}},
+	}
+
+	for _, test := range tests {
+		diagnostics := commitDiagnostics(DiagnosticConfig{}, test.Text)
+		if len(diagnostics) != len(test.Expected) {
+			t.Errorf("Expected %d diagnostics, got %v", len(test.Expected), diagnostics)
+			continue
+		}
+		for i, expected := range test.Expected {
+			got := diagnostics[i]
+			if got.Range != expected.Range || got.Code != expected.Code {
+				t.Errorf("Expected %s at %v, got %s at %v", expected.Code, expected.Range, got.Code, got.Range)
+			}
+			if (got.Data == nil) != (expected.Data == nil) {
+				t.Errorf("Expected data %v, got %v", expected.Data, got.Data)
+			} else if got.Data != nil && (got.Data.Title != expected.Data.Title || *got.Data.Fix != *expected.Data.Fix) {
+				t.Errorf("Expected fix %v, got %v", *expected.Data.Fix, *got.Data.Fix)
+			}
+		}
+	}
+}
+
+func TestCommitVerbForms(t *testing.T) {
+	tests := map[string]string{
+		"checks": "check", "switches": "switch", "fixes": "fix", "stopped": "stop", "added": "add",
+		"simplifies": "simplify", "wrote": "write", "splitting": "split", "setting": "set", "running": "run",
+		"written": "write", "made": "make",
+	}
+	for form, expected := range tests {
+		if verb := commitVerbForms[form]; verb != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, form, verb)
+		}
+	}
+	for _, form := range []string{"checkes", "addes", "stoped", "spliting", "splitted", "setted", "runned", "maked", "writed"} {
+		if verb, ok := commitVerbForms[form]; ok {
+			t.Errorf("Expected no verb for %q, got %q", form, verb)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package lsp
+
+import (
+	"go/ast"
+	"go/parser"
+	gotoken "go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerParser("go", goSegments)
+}
+
+var (
+	goDirectiveRegex = regexp.MustCompile(`^//(?:line |extern |export |[a-z0-9]+:[a-z0-9])`)
+	goListRegex      = regexp.MustCompile(`^(?:[-*+•]|\d+[.)])[ \t]+`)
+	goLinkDefRegex   = regexp.MustCompile(`^\[([^\]]+)\]:[ \t]+\S+$`)
+	goDocLinkRegex   = regexp.MustCompile(`^\[\*?(?:[\w/.-]+\.)?\w+(?:\.\w+)?\]`)
+	goURLRegex       = regexp.MustCompile(`^https?://[^\s\[\]<>]*[^\s\[\]<>.,;:!?)'"]`)
+	goVerbRegex      = regexp.MustCompile(`%[-+# 0]*(?:\[\d+\])?(?:\d+|\*)?(?:\.(?:\d+|\*)?)?[a-zA-Z]`)
+)
+
+// goSegments extracts the doc comments of a Go file, following the syntax of
+// Go doc comments: code blocks are skipped, and list items and headings are
+// segments of their own. String literals passed to the message functions of
+// the config, like errors.New, are checked too, with their format verbs
+// replaced by a placeholder.
+func goSegments(doc *document) []segment {
+	fset := gotoken.NewFileSet()
+	// A file that doesn't parse still has the declarations before the error
+	file, _ := parser.ParseFile(fset, "", doc.text, parser.ParseComments)
+	if file == nil {
+		return []segment{}
+	}
+	offset := func(pos gotoken.Pos) int {
+		return fset.Position(pos).Offset
+	}
+
+	segments := []segment{}
+	seen := map[*ast.CommentGroup]bool{}
+	addDoc := func(group *ast.CommentGroup) {
+		if group != nil && !seen[group] {
+			seen[group] = true
+			segments = append(segments, goDocSegments(doc, goCommentLines(doc, group, offset))...)
+		}
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.File:
+			addDoc(node.Doc)
+		case *ast.FuncDecl:
+			addDoc(node.Doc)
+		case *ast.GenDecl:
+			addDoc(node.Doc)
+		case *ast.TypeSpec:
+			addDoc(node.Doc)
+		case *ast.ValueSpec:
+			addDoc(node.Doc)
+		case *ast.Field:
+			addDoc(node.Doc)
+		case *ast.CallExpr:
+			if !isMessageFunc(doc.messageFuncs, node.Fun) {
+				break
+			}
+			for _, arg := range node.Args {
+				if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == gotoken.STRING {
+					s := goString(doc, offset(lit.Pos()), offset(lit.End()))
+					s = excludeInline(s, []Exclusion{{Name: "verbs", Inline: goVerbRegex}})
+					if hasProse(s.text) {
+						segments = append(segments, s)
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].offsets[0] < segments[j].offsets[0]
+	})
+	return segments
+}
+
+// isMessageFunc reports whether fun is one of funcs. A name without a
+// package, like "Printf", matches the methods of any value.
+func isMessageFunc(funcs []string, fun ast.Expr) bool {
+	name := goExprName(fun)
+	if name == "" {
+		return false
+	}
+	for _, f := range funcs {
+		if name == f || strings.HasSuffix(name, "."+f) {
+			return true
+		}
+	}
+	return false
+}
+
+// goExprName returns the dotted name of an identifier or selector, like
+// "s.Logger.Printf", or "" for other expressions.
+func goExprName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		if x := goExprName(expr.X); x != "" {
+			return x + "." + expr.Sel.Name
+		}
+	}
+	return ""
+}
+
+// goString returns the value of the string literal between start and end.
+// Every character of an escape sequence maps to the whole sequence.
+func goString(doc *document, start, end int) segment {
+	s := segment{}
+	if doc.text[start] == '`' {
+		s.add(doc, start+1, end-1)
+		return s
+	}
+
+	for i := start + 1; i < end-1; {
+		if doc.text[i] != '\\' {
+			s.add(doc, i, i+1)
+			i++
+			continue
+		}
+		value, _, tail, err := strconv.UnquoteChar(doc.text[i:end-1], '"')
+		if err != nil {
+			s.add(doc, i, end-1)
+			break
+		}
+		next := end - 1 - len(tail)
+		s.replace(string(value), i, next)
+		i = next
+	}
+	return s
+}
+
+// goLine is a line of a comment without its comment markers. indented lines
+// start with a space or tab after the "// ".
+type goLine struct {
+	start    int
+	end      int
+	indented bool
+}
+
+// goCommentLines returns the lines of a comment group. Directives like
+// //go:generate are dropped.
+func goCommentLines(doc *document, group *ast.CommentGroup, offset func(gotoken.Pos) int) []goLine {
+	lines := []goLine{}
+	addLine := func(start, end int) {
+		if start < end && doc.text[start] == ' ' {
+			start++
+		}
+		text := doc.text[start:end]
+		end = start + len(strings.TrimRight(text, " \t\r"))
+		indented := start < end && (doc.text[start] == ' ' || doc.text[start] == '\t')
+		lines = append(lines, goLine{start, end, indented})
+	}
+
+	for _, comment := range group.List {
+		start, end := offset(comment.Pos()), offset(comment.End())
+		if strings.HasPrefix(comment.Text, "//") {
+			if !goDirectiveRegex.MatchString(comment.Text) {
+				addLine(start+2, end)
+			}
+			continue
+		}
+
+		// Lines of /* */ comments may start with " * "
+		for i := start + 2; i < end-2; {
+			lineEnd := strings.IndexByte(doc.text[i:end-2], '\n')
+			if lineEnd == -1 {
+				lineEnd = end - 2
+			} else {
+				lineEnd += i
+			}
+			from := i
+			if i > start+2 {
+				for from < lineEnd && (doc.text[from] == ' ' || doc.text[from] == '\t') {
+					from++
+				}
+				if from < lineEnd && doc.text[from] == '*' {
+					from++
+				}
+			}
+			addLine(from, lineEnd)
+			i = lineEnd + 1
+		}
+	}
+	return lines
+}
+
+// goDocSegments splits the lines of a doc comment into paragraphs, headings
+// and list items. Indented lines that aren't a list are code blocks, which
+// are skipped together with link definitions.
+func goDocSegments(doc *document, lines []goLine) []segment {
+	segments := []segment{}
+	current := segment{}
+	flush := func() {
+		if hasProse(current.text) {
+			segments = append(segments, current)
+		}
+		current = segment{}
+	}
+
+	// Link definitions keep the text of their links
+	links := map[string]bool{}
+	for _, line := range lines {
+		if match := goLinkDefRegex.FindStringSubmatch(doc.text[line.start:line.end]); match != nil {
+			links[match[1]] = true
+		}
+	}
+
+	list := false
+	for i, line := range lines {
+		text := doc.text[line.start:line.end]
+		blank := line.start == line.end
+		switch {
+		case blank:
+			flush()
+			// A list goes on across blank lines up to an unindented line
+			if list && (i+1 == len(lines) || !lines[i+1].indented) {
+				list = false
+			}
+			continue
+		case goLinkDefRegex.MatchString(text):
+			flush()
+			continue
+		case line.indented:
+			trimmed := strings.TrimLeft(text, " \t")
+			start := line.start + len(text) - len(trimmed)
+			if marker := goListRegex.FindString(trimmed); marker != "" && (list || len(current.text) == 0) {
+				flush()
+				list = true
+				start += len(marker)
+			} else if !list {
+				// A code block
+				flush()
+				continue
+			}
+			if len(current.text) > 0 {
+				current.join(" ", start-1)
+			}
+			current.add(doc, start, line.end)
+			continue
+		}
+
+		list = false
+		previousBlank := i == 0 || lines[i-1].start == lines[i-1].end
+		nextBlank := i+1 == len(lines) || lines[i+1].start == lines[i+1].end
+		if strings.HasPrefix(text, "# ") && previousBlank && nextBlank {
+			flush()
+			current.add(doc, line.start+2, line.end)
+			flush()
+			continue
+		}
+		if len(current.text) > 0 {
+			current.join(" ", line.start-1)
+		}
+		current.add(doc, line.start, line.end)
+	}
+	flush()
+
+	for i, s := range segments {
+		segments[i] = goDocInline(s, links)
+	}
+	return segments
+}
+
+// goDocInline replaces doc links like [io.Reader], URLs and `code` by a
+// placeholder. Links with a definition keep their text.
+func goDocInline(s segment, links map[string]bool) segment {
+	result := segment{}
+	text := s.text
+	placeholder := func(start, end int, text string) {
+		result.replace(text, s.offsets[start], s.offsets[end-1]+1)
+	}
+
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == '[':
+			if end := strings.IndexByte(text[i:], ']'); end != -1 && links[text[i+1:i+end]] {
+				result.append(s.slice(i+1, i+end))
+				i += end + 1
+				continue
+			}
+			if match := goDocLinkRegex.FindString(text[i:]); match != "" {
+				placeholder(i, i+len(match), codePlaceholder)
+				i += len(match)
+				continue
+			}
+		case c == '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end > 0 {
+				placeholder(i, i+end+2, codePlaceholder)
+				i += end + 2
+				continue
+			}
+		case c == 'h' && (i == 0 || text[i-1] == ' ') && goURLRegex.MatchString(text[i:]):
+			end := i + len(goURLRegex.FindString(text[i:]))
+			placeholder(i, end, urlPlaceholder)
+			i = end
+			continue
+		}
+		result.append(s.slice(i, i+1))
+		i++
+	}
+	return result
+}
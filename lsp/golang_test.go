@@ -0,0 +1,65 @@
+package lsp
+
+import "testing"
+
+func TestGoSegments(t *testing.T) {
+	text := `// Package sample shows how doc comments are checked.
+//
+// # Overview
+//
+// It reads a [Config] from disk, see [io.Reader] and [the docs].
+//
+//	code := sample.New()
+//
+//   - First item of a list.
+//   - Second item that
+//     continues here.
+//
+// [the docs]: https://example.com/docs
+package sample
+
+import "errors"
+
+//go:generate stringer -type=Kind
+
+// Kind is the kind of a thing.
+type Kind int
+
+/*
+ * New returns a new thing.
+ */
+func New(name string) error {
+	// Not a doc comment.
+	if name == "" {
+		return errors.New("name is emtpy")
+	}
+	return fmt.Errorf("can't create %q: \"%w\"\n", name, err)
+}
+`
+
+	expected := []Sentence{
+		{Text: "Package sample shows how doc comments are checked.", Range: Range{Position{0, 3}, Position{0, 53}}},
+		{Text: "Overview", Range: Range{Position{2, 5}, Position{2, 13}}},
+		{Text: "It reads a ⟦code⟧ from disk, see ⟦code⟧ and the docs.", Range: Range{Position{4, 3}, Position{4, 65}}},
+		{Text: "First item of a list.", Range: Range{Position{8, 7}, Position{8, 28}}},
+		{Text: "Second item that continues here.", Range: Range{Position{9, 7}, Position{10, 22}}},
+		{Text: "Kind is the kind of a thing.", Range: Range{Position{19, 3}, Position{19, 31}}},
+		{Text: "New returns a new thing.", Range: Range{Position{23, 3}, Position{23, 27}}},
+		{Text: "name is emtpy", Range: Range{Position{28, 21}, Position{28, 34}}},
+		{Text: "can't create ⟦code⟧: \"⟦code⟧\"", Range: Range{Position{30, 20}, Position{30, 43}}},
+	}
+
+	options := parseOptions{language: defaultLanguage, messageFuncs: []string{"errors.New", "Errorf"}}
+	result := parseSegments(text, options, goSegments)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i := range expected {
+		testSentence(t, result[i], expected[i])
+	}
+
+	// String literals are only checked for configured functions
+	if result := parseSegments(text, parseOptions{language: defaultLanguage}, goSegments); len(result) != len(expected)-2 {
+		t.Errorf("Expected %d sentences, got %d: %v", len(expected)-2, len(result), result)
+	}
+}
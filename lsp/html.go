@@ -0,0 +1,143 @@
+package lsp
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTagRegex       = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)((?:\s+[a-zA-Z_:][a-zA-Z0-9_.:-]*(?:\s*=\s*(?:[^"'=<>` + "`" + `\x00-\x20]+|'[^']*'|"[^"]*"))?)*)\s*/?>`)
+	htmlCommentRegex   = regexp.MustCompile(`(?s)^<!--.*?-->`)
+	htmlAttributeRegex = regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_.:-]*)(?:\s*=\s*(?:([^"'=<>` + "`" + `\x00-\x20]+)|'([^']*)'|"([^"]*)"))?`)
+)
+
+// proseTags are the HTML elements whose text is checked.
+var proseTags = map[string]bool{
+	"p": true, "summary": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "dt": true, "dd": true, "td": true, "th": true, "caption": true, "figcaption": true,
+	"blockquote": true,
+}
+
+// inlineTags don't interrupt the text of the element they are in.
+var inlineTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "cite": true, "del": true, "em": true, "i": true, "img": true,
+	"ins": true, "kbd": true, "mark": true, "q": true, "s": true, "small": true, "span": true,
+	"strong": true, "sub": true, "sup": true, "time": true, "u": true, "var": true, "wbr": true,
+}
+
+// skippedTags hold text that is never prose.
+var skippedTags = map[string]bool{
+	"script": true, "style": true, "pre": true, "textarea": true, "template": true, "svg": true, "math": true,
+}
+
+// htmlSegments extracts the text of the prose elements in an HTML block and
+// the alt texts of its images. Comments and all other text are skipped.
+func htmlSegments(s segment) []segment {
+	segments := []segment{}
+	current := segment{}
+	flush := func() {
+		if strings.TrimSpace(current.text) != "" {
+			segments = append(segments, current)
+		}
+		current = segment{}
+	}
+
+	text := s.text
+	prose := 0
+	skipping := ""
+	for i := 0; i < len(text); {
+		if text[i] == '<' {
+			if comment := htmlCommentRegex.FindString(text[i:]); comment != "" {
+				i += len(comment)
+				continue
+			}
+			if match := htmlTagRegex.FindStringSubmatchIndex(text[i:]); match != nil {
+				closing := match[3] > match[2]
+				name := strings.ToLower(text[i+match[4] : i+match[5]])
+				end := i + match[1]
+
+				switch {
+				case skipping != "":
+					if closing && name == skipping {
+						skipping = ""
+					}
+				case name == "code" && !closing && prose > 0:
+					// Inline code in prose becomes a placeholder
+					if close := strings.Index(strings.ToLower(text[end:]), "</code>"); close != -1 {
+						end += close + len("</code>")
+						current.replace(codePlaceholder, s.offsets[i], s.offsets[end-1]+1)
+					}
+				case skippedTags[name] && !closing:
+					skipping = name
+				case name == "img" || name == "area":
+					segments = append(segments, attributeSegments(s.slice(i+match[6], i+match[7]), "alt")...)
+				case name == "br":
+					current.join(" ", s.offsets[i])
+				case inlineTags[name]:
+				default:
+					flush()
+					if proseTags[name] && closing {
+						prose = max(prose-1, 0)
+					} else if proseTags[name] {
+						prose++
+					}
+				}
+
+				i = end
+				continue
+			}
+		}
+
+		// Text runs up to the next tag
+		next := strings.IndexByte(text[i+1:], '<')
+		if next == -1 {
+			next = len(text)
+		} else {
+			next += i + 1
+		}
+		if prose > 0 && skipping == "" {
+			current.append(decodeEntities(s.slice(i, next)))
+		}
+		i = next
+	}
+	flush()
+
+	return segments
+}
+
+// attributeSegments returns the values of the named attributes in the
+// attribute list of a tag.
+func attributeSegments(attributes segment, name string) []segment {
+	segments := []segment{}
+
+	for _, match := range htmlAttributeRegex.FindAllStringSubmatchIndex(attributes.text, -1) {
+		if !strings.EqualFold(attributes.text[match[2]:match[3]], name) {
+			continue
+		}
+		for group := 4; group < len(match); group += 2 {
+			if match[group] != -1 && match[group+1] > match[group] {
+				segments = append(segments, decodeEntities(attributes.slice(match[group], match[group+1])))
+			}
+		}
+	}
+
+	return segments
+}
+
+// decodeEntities replaces the character references in a segment.
+func decodeEntities(s segment) segment {
+	result := segment{}
+	for i := 0; i < len(s.text); {
+		if s.text[i] == '&' {
+			if match := entityRegex.FindString(s.text[i:]); match != "" && html.UnescapeString(match) != match {
+				result.replace(html.UnescapeString(match), s.offsets[i], s.offsets[i+len(match)-1]+1)
+				i += len(match)
+				continue
+			}
+		}
+		result.append(s.slice(i, i+1))
+		i++
+	}
+	return result
+}
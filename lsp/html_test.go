@@ -0,0 +1,42 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestHTMLBlocks(t *testing.T) {
+	text := `<!--
+A multi-line comment
+that isn't checked.
+-->
+
+<details>
+<summary>Click to expand</summary>
+<div class="note">Layout text.</div>
+<p>First &amp; <em>only</em>
+paragraph with <code>x</code>.</p>
+<img src="a.png" alt="A cat on a mat">
+</details>
+
+<pre>
+Not prose.
+</pre>
+
+Text with <span>inline</span> tags<br>and <img src="b.png" alt='a dog'>.
+`
+	expected := []Sentence{
+		{Text: "Click to expand", Range: Range{Position{6, 9}, Position{6, 24}}},
+		{Text: "First & only paragraph with ⟦code⟧.", Range: Range{Position{8, 3}, Position{9, 30}}},
+		{Text: "A cat on a mat", Range: Range{Position{10, 22}, Position{10, 36}}},
+		{Text: "Text with inline tags and .", Range: Range{Position{17, 0}, Position{17, 72}}},
+		{Text: "a dog", Range: Range{Position{17, 64}, Position{17, 69}}},
+	}
+
+	result := parse(text)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i, sentence := range expected {
+		testSentence(t, result[i], sentence)
+	}
+}
@@ -6,8 +6,9 @@ type InitializeRequest struct {
 }
 
 type InitializeParams struct {
-	ProcessID  *int  `json:"processId,omitempty"`
-	ClientInfo *Info `json:"clientInfo,omitempty"`
+	ProcessID  *int   `json:"processId,omitempty"`
+	ClientInfo *Info  `json:"clientInfo,omitempty"`
+	RootURI    string `json:"rootUri,omitempty"`
 }
 
 type Info struct {
@@ -26,8 +27,11 @@ type InitializeResult struct {
 }
 
 type ServerCapabilities struct {
-	TextDocumentSync    TextDocumentSyncOptions `json:"textDocumentSync"`
-	DiagnosticsProvider DiagnosticsOptions      `json:"diagnosticsProvider"`
+	TextDocumentSync     TextDocumentSyncOptions     `json:"textDocumentSync"`
+	DiagnosticsProvider  DiagnosticsOptions          `json:"diagnosticsProvider"`
+	CompletionProvider   CompletionOptions           `json:"completionProvider"`
+	CodeActionProvider   bool                        `json:"codeActionProvider"`
+	NotebookDocumentSync NotebookDocumentSyncOptions `json:"notebookDocumentSync"`
 }
 
 type DiagnosticsOptions struct {
@@ -63,6 +67,14 @@ func NewInitializeResponse(ID *int) *InitializeResponse {
 					InterFileDependencies: false,
 					WorkspaceDiagnostics:  false,
 				},
+				CompletionProvider: CompletionOptions{ResolveProvider: false},
+				CodeActionProvider: true,
+				NotebookDocumentSync: NotebookDocumentSyncOptions{
+					NotebookSelector: []NotebookSelector{
+						{Notebook: NotebookDocumentFilter{NotebookType: "jupyter-notebook"}},
+					},
+					Save: true,
+				},
 			},
 		},
 	}
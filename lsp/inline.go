@@ -0,0 +1,307 @@
+package lsp
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Placeholders stand in for text the model must not touch. The system prompt
+// tells the model to keep them as they are.
+const (
+	codePlaceholder = "⟦code⟧"
+	urlPlaceholder  = "⟦url⟧"
+)
+
+var (
+	autolinkRegex = regexp.MustCompile(`^<[A-Za-z][A-Za-z0-9.+-]{1,31}:[^<>\x00-\x20]*>`)
+	emailRegex    = regexp.MustCompile(`^<[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*>`)
+	entityRegex   = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[A-Za-z][A-Za-z0-9]{1,31});`)
+)
+
+// slice returns the part of a segment between two byte offsets of its text.
+func (s segment) slice(start, end int) segment {
+	return segment{text: s.text[start:end], offsets: s.offsets[start:end]}
+}
+
+func (s *segment) append(other segment) {
+	s.text += other.text
+	s.offsets = append(s.offsets, other.offsets...)
+}
+
+// replace appends text standing in for the document text between start and
+// end, so that a range over all of text maps back to all of the original.
+func (s *segment) replace(text string, start, end int) {
+	s.text += text
+	for i := 0; i < len(text); i++ {
+		if i == len(text)-1 {
+			s.offsets = append(s.offsets, end-1)
+		} else {
+			s.offsets = append(s.offsets, start)
+		}
+	}
+}
+
+// inlinePiece is either plain output or a run of emphasis delimiters that is
+// dropped once it is matched with another run.
+type inlinePiece struct {
+	out      segment
+	delim    byte
+	canOpen  bool
+	canClose bool
+	matched  bool
+}
+
+// stripInline removes inline markdown markup from a segment: emphasis,
+// strikethrough, links, images, raw HTML, escapes and entities. Code spans and
+// autolinks are replaced by placeholders. Every byte left keeps its offset in
+// the document.
+func stripInline(s segment) segment {
+	pieces := []*inlinePiece{}
+	current := &inlinePiece{}
+	pieces = append(pieces, current)
+
+	emit := func(out segment) {
+		current.out.append(out)
+	}
+	emitRun := func(piece *inlinePiece) {
+		pieces = append(pieces, piece)
+		current = &inlinePiece{}
+		pieces = append(pieces, current)
+	}
+
+	text := s.text
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]):
+			emit(s.slice(i+1, i+2))
+			i += 2
+
+		case c == '`':
+			n := runLength(text, i)
+			closing := findCodeSpanEnd(text, i+n, n)
+			if closing == -1 {
+				emit(s.slice(i, i+n))
+				i += n
+				continue
+			}
+			out := segment{}
+			out.replace(codePlaceholder, s.offsets[i], s.offsets[closing+n-1]+1)
+			emit(out)
+			i = closing + n
+
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			if _, _, end := parseLink(text, i+1); end != -1 {
+				i = end
+				continue
+			}
+			emit(s.slice(i, i+1))
+			i++
+
+		case c == '[':
+			labelStart, labelEnd, end := parseLink(text, i)
+			if end == -1 {
+				emit(s.slice(i, i+1))
+				i++
+				continue
+			}
+			emit(stripInline(s.slice(labelStart, labelEnd)))
+			i = end
+
+		case c == '<':
+			match := autolinkRegex.FindString(text[i:])
+			if match == "" {
+				match = emailRegex.FindString(text[i:])
+			}
+			if match == "" {
+				// Inline HTML and comments are dropped, their text is kept
+				tag := htmlCommentRegex.FindString(text[i:])
+				if tag == "" {
+					tag = htmlTagRegex.FindString(text[i:])
+				}
+				switch {
+				case tag == "":
+					emit(s.slice(i, i+1))
+					i++
+				case strings.EqualFold(strings.Trim(tag, "</ >"), "br"):
+					out := segment{}
+					out.join(" ", s.offsets[i])
+					emit(out)
+					i += len(tag)
+				default:
+					i += len(tag)
+				}
+				continue
+			}
+			out := segment{}
+			out.replace(urlPlaceholder, s.offsets[i], s.offsets[i+len(match)-1]+1)
+			emit(out)
+			i += len(match)
+
+		case c == '&':
+			match := entityRegex.FindString(text[i:])
+			decoded := html.UnescapeString(match)
+			if match == "" || decoded == match {
+				emit(s.slice(i, i+1))
+				i++
+				continue
+			}
+			out := segment{}
+			out.replace(decoded, s.offsets[i], s.offsets[i+len(match)-1]+1)
+			emit(out)
+			i += len(match)
+
+		case c == '*' || c == '_' || c == '~':
+			n := runLength(text, i)
+			before, _ := utf8.DecodeLastRuneInString(text[:i])
+			after, _ := utf8.DecodeRuneInString(text[i+n:])
+			if i == 0 {
+				before = ' '
+			}
+			if i+n == len(text) {
+				after = ' '
+			}
+			leftFlanking := !unicode.IsSpace(after) && (!isPunctuation(after) || unicode.IsSpace(before) || isPunctuation(before))
+			rightFlanking := !unicode.IsSpace(before) && (!isPunctuation(before) || unicode.IsSpace(after) || isPunctuation(after))
+
+			piece := &inlinePiece{out: s.slice(i, i+n), delim: c}
+			if c == '_' {
+				piece.canOpen = leftFlanking && (!rightFlanking || isPunctuation(before))
+				piece.canClose = rightFlanking && (!leftFlanking || isPunctuation(after))
+			} else {
+				piece.canOpen = leftFlanking
+				piece.canClose = rightFlanking
+			}
+			emitRun(piece)
+			i += n
+
+		default:
+			_, size := utf8.DecodeRuneInString(text[i:])
+			emit(s.slice(i, i+size))
+			i += size
+		}
+	}
+
+	// Match closing delimiter runs with the nearest opening run of the same
+	// character before them.
+	for i, closer := range pieces {
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			opener := pieces[j]
+			if opener.delim == closer.delim && opener.canOpen && !opener.matched {
+				opener.matched = true
+				closer.matched = true
+				break
+			}
+		}
+	}
+
+	result := segment{}
+	for _, piece := range pieces {
+		if !piece.matched {
+			result.append(piece.out)
+		}
+	}
+	return result
+}
+
+func runLength(text string, i int) int {
+	n := 0
+	for i+n < len(text) && text[i+n] == text[i] {
+		n++
+	}
+	return n
+}
+
+// findCodeSpanEnd returns the start of the backtick run of length n closing a
+// code span, or -1.
+func findCodeSpanEnd(text string, from, n int) int {
+	for i := from; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		length := runLength(text, i)
+		if length == n {
+			return i
+		}
+		i += length
+	}
+	return -1
+}
+
+// parseLink parses a link starting at the "[" at offset i. It returns the
+// bounds of the link text and the offset after the link, or -1 when there is
+// no inline or full reference link there.
+func parseLink(text string, i int) (int, int, int) {
+	depth := 0
+	labelEnd := -1
+	for j := i; j < len(text) && labelEnd == -1; j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '`':
+			n := runLength(text, j)
+			if closing := findCodeSpanEnd(text, j+n, n); closing != -1 {
+				j = closing + n - 1
+			} else {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				labelEnd = j
+			}
+		}
+	}
+	if labelEnd == -1 || labelEnd+1 >= len(text) {
+		return 0, 0, -1
+	}
+
+	closer := byte(')')
+	switch text[labelEnd+1] {
+	case '(':
+	case '[':
+		closer = ']'
+	default:
+		return 0, 0, -1
+	}
+
+	depth = 0
+	for j := labelEnd + 1; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '<':
+			if closer == ')' {
+				if end := strings.IndexByte(text[j:], '>'); end != -1 {
+					j += end
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if j < len(text) && text[j] == closer && (closer == ']' || depth == 0) {
+			return i + 1, labelEnd, j + 1
+		}
+	}
+
+	return 0, 0, -1
+}
+
+func isASCIIPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) != -1
+}
+
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,85 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripInline(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected string
+	}{
+		{"This is **bold** and _italic_ text", "This is bold and italic text"},
+		{"Read [the docs](https://example.com \"Docs\") or [that][ref]", "Read the docs or that"},
+		{"Call `parse()` with ``a ` b``", "Call ⟦code⟧ with ⟦code⟧"},
+		{"Visit <https://example.com> or ![logo](logo.png)", "Visit ⟦url⟧ or "},
+		{"Use snake_case_names and 5 * 3 * 2", "Use snake_case_names and 5 * 3 * 2"},
+		{"Escaped \\*stars\\* and &amp; ~~gone~~", "Escaped *stars* and & gone"},
+		{"A [**nested** link](url)", "A nested link"},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(test.Text)
+		s := segment{}
+		s.add(doc, 0, len(test.Text))
+
+		result := stripInline(s)
+		if result.text != test.Expected {
+			t.Errorf("Expected %q, got %q", test.Expected, result.text)
+		}
+		if len(result.offsets) != len(result.text) {
+			t.Errorf("Expected %d offsets, got %d", len(result.text), len(result.offsets))
+		}
+	}
+}
+
+func TestStrippedSentenceRanges(t *testing.T) {
+	text := "She **go** to the `store` yesterday."
+	sentence := parse(text)[0]
+
+	if sentence.Text != "She go to the ⟦code⟧ yesterday." {
+		t.Fatalf("Unexpected text %q", sentence.Text)
+	}
+
+	tests := []struct {
+		Word     string
+		Expected Range
+	}{
+		{"go", Range{Position{0, 6}, Position{0, 8}}},
+		{codePlaceholder, Range{Position{0, 18}, Position{0, 25}}},
+		{"yesterday", Range{Position{0, 26}, Position{0, 35}}},
+	}
+
+	for _, test := range tests {
+		start := strings.Index(sentence.Text, test.Word)
+		result := sentence.rangeOf(start, start+len(test.Word))
+		if result != test.Expected {
+			t.Errorf("Expected %s at %v, got %v", test.Word, test.Expected, result)
+		}
+	}
+}
+
+func TestUnfinishedLinks(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected string
+	}{
+		{"[a](\\", "[a](\\"},
+		{"[a][b\\", "[a][b\\"},
+		{"x [a](b\\", "x [a](b\\"},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(test.Text)
+		s := segment{}
+		s.add(doc, 0, len(test.Text))
+
+		if links := findLinks(s); len(links) != 0 {
+			t.Errorf("Expected no links in %q, got %v", test.Text, links)
+		}
+		if result := parse(test.Text); len(result) != 1 || result[0].Text != test.Expected {
+			t.Errorf("Expected %q, got %v", test.Expected, result)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerParser("latex", latexSegments)
+	registerParser("tex", latexSegments)
+}
+
+// latexSections start a segment of their own with their argument.
+var latexSections = map[string]bool{
+	"part": true, "chapter": true, "section": true, "subsection": true, "subsubsection": true,
+	"paragraph": true, "subparagraph": true, "caption": true, "footnote": true, "marginpar": true,
+}
+
+// latexFormatting keep the text of their argument in the sentence.
+var latexFormatting = map[string]bool{
+	"emph": true, "textbf": true, "textit": true, "textsc": true, "textsl": true, "textup": true,
+	"textmd": true, "textrm": true, "textsf": true, "textnormal": true, "underline": true, "uline": true,
+	"mbox": true, "text": true, "hbox": true, "enquote": true, "textquote": true,
+}
+
+// latexReferences become a placeholder together with their arguments.
+var latexReferences = map[string]string{
+	"cite": codePlaceholder, "citep": codePlaceholder, "citet": codePlaceholder, "citeauthor": codePlaceholder,
+	"citeyear": codePlaceholder, "parencite": codePlaceholder, "textcite": codePlaceholder,
+	"autocite": codePlaceholder, "ref": codePlaceholder, "eqref": codePlaceholder, "pageref": codePlaceholder,
+	"autoref": codePlaceholder, "cref": codePlaceholder, "Cref": codePlaceholder, "nameref": codePlaceholder,
+	"texttt": codePlaceholder, "path": codePlaceholder, "url": urlPlaceholder, "nolinkurl": urlPlaceholder,
+}
+
+// latexSkipped are dropped together with their arguments. Declarations like
+// \centering or \small have none.
+var latexSkipped = map[string]bool{
+	"label": true, "index": true, "includegraphics": true, "input": true, "include": true, "vspace": true,
+	"hspace": true, "setlength": true, "setcounter": true, "addtocounter": true, "newcommand": true,
+	"renewcommand": true, "providecommand": true, "newenvironment": true, "usepackage": true,
+	"bibliography": true, "bibliographystyle": true, "addbibresource": true, "pagestyle": true,
+	"thispagestyle": true, "hypersetup": true, "graphicspath": true, "def": true, "color": true,
+	"centering": true, "noindent": true, "indent": true, "maketitle": true, "tableofcontents": true,
+	"newpage": true, "clearpage": true, "appendix": true, "printbibliography": true, "hline": true,
+	"toprule": true, "midrule": true, "bottomrule": true, "cline": true, "medskip": true, "bigskip": true,
+	"smallskip": true, "hfill": true, "vfill": true, "raggedright": true, "raggedleft": true,
+	"tiny": true, "scriptsize": true, "footnotesize": true, "small": true, "normalsize": true,
+	"large": true, "Large": true, "LARGE": true, "huge": true, "Huge": true, "bfseries": true,
+	"itshape": true, "ttfamily": true, "rmfamily": true, "sffamily": true, "protect": true,
+	"frontmatter": true, "mainmatter": true, "backmatter": true, "nocite": true,
+}
+
+// latexSpaces break lines or add space between words.
+var latexSpaces = map[string]bool{
+	"newline": true, "linebreak": true, "quad": true, "qquad": true, "enspace": true, "enskip": true,
+	"thinspace": true, "space": true, "nobreakspace": true, "hskip": true,
+}
+
+// latexMath are the environments typeset as math, which become a
+// placeholder. latexVerbatim are skipped, and the arguments of latexTables
+// are skipped too.
+var (
+	latexMath = map[string]bool{
+		"math": true, "displaymath": true, "equation": true, "equation*": true, "align": true, "align*": true,
+		"gather": true, "gather*": true, "multline": true, "multline*": true, "eqnarray": true,
+		"eqnarray*": true, "flalign": true, "flalign*": true,
+	}
+	latexVerbatim = map[string]bool{
+		"verbatim": true, "verbatim*": true, "Verbatim": true, "lstlisting": true, "minted": true,
+		"comment": true, "tikzpicture": true, "thebibliography": true, "filecontents": true,
+	}
+	latexTables = map[string]int{
+		"tabular": 1, "tabular*": 2, "tabularx": 2, "longtable": 1, "array": 1, "minipage": 1,
+		"multicols": 1, "wrapfigure": 2,
+	}
+)
+
+// latexSegments extracts the prose of a LaTeX document: paragraphs, the
+// arguments of sectioning and formatting commands, list items, captions,
+// footnotes and table cells. The preamble, comments, math, verbatim
+// environments, citations, references and labels are skipped. Every byte of
+// prose keeps its offset, so fixes land between the macros.
+func latexSegments(doc *document) []segment {
+	start, end := 0, len(doc.text)
+	if i := strings.Index(doc.text, `\begin{document}`); i != -1 {
+		start = i + len(`\begin{document}`)
+	}
+	if i := strings.LastIndex(doc.text, `\end{document}`); i >= start {
+		end = i
+	}
+
+	p := &latexParser{doc: doc}
+	current := segment{}
+	p.parse(start, end, &current)
+	p.flush(&current)
+
+	// Footnotes and captions end before the paragraph around them
+	sort.SliceStable(p.segments, func(i, j int) bool {
+		return p.segments[i].offsets[0] < p.segments[j].offsets[0]
+	})
+	return p.segments
+}
+
+type latexParser struct {
+	doc      *document
+	segments []segment
+	tables   int
+}
+
+func (p *latexParser) flush(s *segment) {
+	if hasProse(s.text) {
+		p.segments = append(p.segments, *s)
+	}
+	*s = segment{}
+}
+
+// group returns the offset after the group opened by the brace or bracket at
+// i, or -1 when it isn't closed.
+func (p *latexParser) group(i, end int) int {
+	open := p.doc.text[i]
+	close := byte('}')
+	if open == '[' {
+		close = ']'
+	}
+	depth := 0
+	for j := i; j < end; j++ {
+		switch p.doc.text[j] {
+		case '\\':
+			j++
+		case '%':
+			for j < end && p.doc.text[j] != '\n' {
+				j++
+			}
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return -1
+}
+
+// arguments returns the offsets after the optional arguments and the n
+// mandatory arguments following i, with the bounds of the last mandatory
+// one. All adjacent arguments are skipped when n is -1.
+func (p *latexParser) arguments(i, end, n int) (next, argStart, argEnd int) {
+	text := p.doc.text
+	argStart, argEnd = -1, -1
+	if i < end && text[i] == '*' {
+		i++
+	}
+	for i < end {
+		j := i
+		for j < end && (text[j] == ' ' || text[j] == '\t') {
+			j++
+		}
+		if j == end || (text[j] != '[' && text[j] != '{') || (text[j] == '{' && n == 0) {
+			break
+		}
+		close := p.group(j, end)
+		if close == -1 {
+			break
+		}
+		if text[j] == '{' {
+			argStart, argEnd = j+1, close-1
+			n--
+		}
+		i = close
+	}
+	return i, argStart, argEnd
+}
+
+// environment returns the offset after the \end{name} closing the
+// environment whose body starts at i.
+func (p *latexParser) environment(name string, i, end int) int {
+	closing := `\end{` + name + `}`
+	if j := strings.Index(p.doc.text[i:end], closing); j != -1 {
+		return i + j + len(closing)
+	}
+	return end
+}
+
+// parse adds the prose between start and end to current, and adds
+// paragraphs to the segments as they end.
+func (p *latexParser) parse(start, end int, current *segment) {
+	text := p.doc.text
+	for i := start; i < end; {
+		switch c := text[i]; c {
+		case '%':
+			// A comment swallows its line break and the indentation after it,
+			// unless a blank line follows and ends the paragraph
+			for i < end && text[i] != '\n' {
+				i++
+			}
+			j := i + 1
+			for j < end && (text[j] == ' ' || text[j] == '\t' || text[j] == '\r') {
+				j++
+			}
+			if j >= end || text[j] != '\n' {
+				i = j
+			}
+
+		case '\n':
+			j := i + 1
+			for j < end && (text[j] == ' ' || text[j] == '\t' || text[j] == '\r') {
+				j++
+			}
+			if j < end && text[j] == '\n' {
+				p.flush(current)
+				i = j
+			} else {
+				current.add(p.doc, i, i+1)
+				i++
+			}
+
+		case '$':
+			n := runLength(text[:end], i)
+			if n > 2 {
+				n = 2
+			}
+			close := strings.Index(text[i+n:end], text[i:i+n])
+			if close == -1 {
+				i += n
+				continue
+			}
+			close += i + 2*n
+			current.replace(codePlaceholder, i, close)
+			i = close
+
+		case '{', '}':
+			i++
+
+		case '&':
+			p.flush(current)
+			i++
+
+		case '~':
+			current.replace(" ", i, i+1)
+			i++
+
+		case '`', '\'':
+			// ``Quotes'' are typed with pairs of quotes
+			switch {
+			case runLength(text[:end], i) == 2:
+				current.replace(`"`, i, i+2)
+				i += 2
+			case c == '`':
+				current.replace("'", i, i+1)
+				i++
+			default:
+				current.add(p.doc, i, i+1)
+				i++
+			}
+
+		case '\\':
+			i = p.command(i, end, current)
+
+		default:
+			j := i + 1
+			for j < end && !strings.ContainsRune("%\n${}&~`'\\", rune(text[j])) {
+				j++
+			}
+			current.add(p.doc, i, j)
+			i = j
+		}
+	}
+}
+
+// command handles the command starting with the backslash at i, and returns
+// the offset after it.
+func (p *latexParser) command(i, end int, current *segment) int {
+	text := p.doc.text
+	if i+1 == end {
+		return end
+	}
+
+	// Control symbols
+	if c := text[i+1]; !isLetter(c) {
+		switch c {
+		case '\\':
+			next, _, _ := p.arguments(i+2, end, 0)
+			if p.tables > 0 {
+				p.flush(current)
+			} else {
+				current.replace(" ", i, next)
+			}
+			return next
+		case '%', '&', '$', '#', '_', '{', '}':
+			current.add(p.doc, i+1, i+2)
+		case ' ', ',', ';', ':':
+			current.replace(" ", i, i+2)
+		case '(', '[':
+			closing := `\)`
+			if c == '[' {
+				closing = `\]`
+			}
+			close := strings.Index(text[i+2:end], closing)
+			if close == -1 {
+				return end
+			}
+			close += i + 4
+			current.replace(codePlaceholder, i, close)
+			return close
+		case '/', '@':
+		default:
+			current.replace(codePlaceholder, i, i+2)
+		}
+		return i + 2
+	}
+
+	j := i + 1
+	for j < end && isLetter(text[j]) {
+		j++
+	}
+	name := text[i+1 : j]
+
+	switch {
+	case name == "begin" || name == "end":
+		next, argStart, argEnd := p.arguments(j, end, 1)
+		if argStart == -1 {
+			return next
+		}
+		env := text[argStart:argEnd]
+
+		// Display math is often part of a sentence
+		if name == "begin" && latexMath[env] {
+			after := p.environment(env, next, end)
+			current.replace(codePlaceholder, i, after)
+			return after
+		}
+
+		p.flush(current)
+		switch {
+		case name == "end":
+			if _, ok := latexTables[env]; ok {
+				p.tables = max(p.tables-1, 0)
+			}
+		case latexVerbatim[env]:
+			return p.environment(env, next, end)
+		default:
+			if n, ok := latexTables[env]; ok {
+				next, _, _ = p.arguments(next, end, n)
+				p.tables++
+			} else {
+				next, _, _ = p.arguments(next, end, 0)
+			}
+		}
+		return next
+
+	case name == "item" || name == "par":
+		p.flush(current)
+		next, _, _ := p.arguments(j, end, 0)
+		return next
+
+	case name == "verb" || name == "lstinline":
+		// \verb|code| uses any character as its delimiter
+		k := j
+		if k < end && text[k] == '*' {
+			k++
+		}
+		if k < end && text[k] == '{' {
+			if close := p.group(k, end); close != -1 {
+				current.replace(codePlaceholder, i, close)
+				return close
+			}
+		}
+		if k < end {
+			if close := strings.IndexByte(text[k+1:end], text[k]); close != -1 {
+				current.replace(codePlaceholder, i, k+close+2)
+				return k + close + 2
+			}
+		}
+		return end
+
+	case latexSections[name]:
+		next, argStart, argEnd := p.arguments(j, end, 1)
+		if argStart == -1 {
+			return next
+		}
+		if name != "footnote" && name != "marginpar" {
+			p.flush(current)
+		}
+		s := segment{}
+		p.parse(argStart, argEnd, &s)
+		p.flush(&s)
+		return next
+
+	case latexFormatting[name]:
+		next, argStart, argEnd := p.arguments(j, end, 1)
+		if argStart != -1 {
+			p.parse(argStart, argEnd, current)
+		}
+		return next
+
+	case name == "href":
+		next, argStart, argEnd := p.arguments(j, end, 2)
+		if argStart != -1 {
+			p.parse(argStart, argEnd, current)
+		}
+		return next
+
+	case name == "multicolumn" || name == "textcolor" || name == "colorbox":
+		n := 2
+		if name == "multicolumn" {
+			n = 3
+		}
+		next, argStart, argEnd := p.arguments(j, end, n)
+		if argStart != -1 {
+			p.parse(argStart, argEnd, current)
+		}
+		return next
+
+	case latexReferences[name] != "":
+		next, _, _ := p.arguments(j, end, -1)
+		current.replace(latexReferences[name], i, next)
+		return next
+
+	case latexSkipped[name]:
+		next, _, _ := p.arguments(j, end, -1)
+		return next
+
+	case name == "ldots" || name == "dots":
+		current.replace("…", i, j)
+		return p.skipSpace(j, end)
+
+	case latexSpaces[name]:
+		next, _, _ := p.arguments(j, end, 0)
+		current.replace(" ", i, next)
+		return next
+	}
+
+	// Other commands with arguments keep the text of their mandatory
+	// arguments, and the others stand for a word, like \LaTeX
+	next, _, _ := p.arguments(j, end, 0)
+	if after := p.skipSpace(next, end); after < end && text[after] == '{' {
+		return next
+	}
+	current.replace(codePlaceholder, i, next)
+	return next
+}
+
+// skipSpace returns the offset after the spaces ending a command name.
+func (p *latexParser) skipSpace(i, end int) int {
+	for i < end && (p.doc.text[i] == ' ' || p.doc.text[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,93 @@
+package lsp
+
+import "testing"
+
+func TestLatexSegments(t *testing.T) {
+	text := "\\documentclass{article}\n\\title{Skipped title}\n\\begin{document}\n" +
+		"\\section{Introduction}\\label{sec:intro}\n" +
+		"This is \\emph{important}, see~\\cite{knuth84}.\n" +
+		"% A comment.\n" +
+		"So $E = mc^2$ and\n\\begin{equation}\n  a^2 = b\n\\end{equation}\nholds.\\footnote{A footnote.}\n\n" +
+		"\\begin{itemize}\n  \\item[b)] An item with \\texttt{code}.\n\\end{itemize}\n\n" +
+		"\\begin{verbatim}\nskip this\n\\end{verbatim}\n\n" +
+		"\\begin{tabular}{|l|r|}\nName & Description \\\\ \\hline\n\\end{tabular}\n" +
+		"Written in \\LaTeX, ``quoted'' text.\n" +
+		"\\end{document}\n"
+
+	expected := []Sentence{
+		{Text: "Introduction", Range: Range{Position{3, 9}, Position{3, 21}}},
+		{Text: "This is important, see ⟦code⟧.", Range: Range{Position{4, 0}, Position{4, 45}}},
+		{Text: "So ⟦code⟧ and ⟦code⟧ holds.", Range: Range{Position{6, 0}, Position{10, 6}}},
+		{Text: "A footnote.", Range: Range{Position{10, 16}, Position{10, 27}}},
+		{Text: "An item with ⟦code⟧.", Range: Range{Position{13, 12}, Position{13, 39}}},
+		{Text: "Name", Range: Range{Position{21, 0}, Position{21, 4}}},
+		{Text: "Description", Range: Range{Position{21, 7}, Position{21, 18}}},
+		{Text: "Written in ⟦code⟧, \"quoted\" text.", Range: Range{Position{23, 0}, Position{23, 35}}},
+	}
+
+	result := parseSegments(text, parseOptions{language: defaultLanguage}, latexSegments)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i := range expected {
+		testSentence(t, result[i], expected[i])
+	}
+}
+
+func TestLatexFixes(t *testing.T) {
+	text := "This is \\emph{importent} work.\n\nThe \\textbf{cat} sit here.\n"
+	sentences := parseSegments(text, parseOptions{language: defaultLanguage}, latexSegments)
+
+	tests := []struct {
+		Sentence   Sentence
+		Correction string
+		Expected   *TextEdit
+	}{
+		// The fix lands inside the braces
+		{sentences[0], "This is important work.", &TextEdit{Range: Range{Position{0, 14}, Position{0, 23}}, NewText: "important"}},
+		// Fixes spanning a macro would break it
+		{sentences[1], "The cats sits here.", nil},
+	}
+
+	for _, test := range tests {
+		check := SentenceCheck{HasError: true, Correction: test.Correction, Category: CategorySpelling}
+		diagnostics := ConvertCheckToDiagnostics(DiagnosticConfig{}, "file:///test.tex", test.Sentence, check)
+		if len(diagnostics) != 1 {
+			t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+		}
+		fix := (*TextEdit)(nil)
+		if diagnostics[0].Data != nil {
+			fix = diagnostics[0].Data.Fix
+		}
+		if (fix == nil) != (test.Expected == nil) || fix != nil && *fix != *test.Expected {
+			t.Errorf("Expected %v, got %v", test.Expected, fix)
+		}
+	}
+}
+
+func TestLatexCommands(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected []string
+	}{
+		// A comment before a blank line still ends the paragraph
+		{"First paragraph without period\n% c\n\nSecond paragraph.\n", []string{"First paragraph without period", "Second paragraph."}},
+		{"One % c\n  line.\n", []string{"One line."}},
+		// Optional arguments of unknown commands are skipped
+		{"A \\foo[opt]{arg} here and \\bar[x] there.\n", []string{"A arg here and ⟦code⟧ there."}},
+		{"A line\\newline second one\\quad and \\linebreak[4]a third.\n", []string{"A line second one and a third."}},
+	}
+
+	for _, test := range tests {
+		result := parseSegments(test.Text, parseOptions{language: defaultLanguage}, latexSegments)
+		if len(result) != len(test.Expected) {
+			t.Errorf("Expected %q, got %v", test.Expected, result)
+			continue
+		}
+		for i := range test.Expected {
+			if result[i].Text != test.Expected[i] {
+				t.Errorf("Expected %q, got %q", test.Expected[i], result[i].Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package lsp
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// inlineLink is a link or an image in a paragraph. label is the link text
+// or the alt text, and start and end are the document offsets of the whole
+// link.
+type inlineLink struct {
+	image       bool
+	html        bool
+	label       segment
+	hasLabel    bool
+	destination string
+	start       int
+	end         int
+}
+
+// findLinks returns the markdown links and images of a segment, and its HTML
+// images. Images inside link text are included. Code spans are skipped.
+func findLinks(s segment) []inlineLink {
+	links := []inlineLink{}
+
+	text := s.text
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+
+		case '`':
+			n := runLength(text, i)
+			if closing := findCodeSpanEnd(text, i+n, n); closing != -1 {
+				i = closing + n - 1
+			} else {
+				i += n - 1
+			}
+
+		case '!', '[':
+			start := i
+			if text[i] == '!' {
+				if i+1 == len(text) || text[i+1] != '[' {
+					continue
+				}
+				i++
+			}
+			labelStart, labelEnd, end := parseLink(text, i)
+			if end == -1 {
+				continue
+			}
+			label := s.slice(labelStart, labelEnd)
+			links = append(links, inlineLink{
+				image:       text[start] == '!',
+				label:       label,
+				hasLabel:    true,
+				destination: linkDestination(text[labelEnd+1 : end]),
+				start:       s.offsets[start],
+				end:         s.offsets[end-1] + 1,
+			})
+			links = append(links, findLinks(label)...)
+			i = end - 1
+
+		case '<':
+			match := htmlTagRegex.FindStringSubmatchIndex(text[i:])
+			if match == nil || match[3] > match[2] {
+				continue
+			}
+			if name := strings.ToLower(text[i+match[4] : i+match[5]]); name == "img" {
+				attributes := s.slice(i+match[6], i+match[7])
+				alt, hasAlt := htmlAttribute(attributes, "alt")
+				src, _ := htmlAttribute(attributes, "src")
+				links = append(links, inlineLink{
+					image:       true,
+					html:        true,
+					label:       alt,
+					hasLabel:    hasAlt,
+					destination: src.text,
+					start:       s.offsets[i],
+					end:         s.offsets[i+match[1]-1] + 1,
+				})
+			}
+			i += match[1] - 1
+		}
+	}
+
+	return links
+}
+
+// linkDestination returns the URL of an inline link from the part after its
+// text, like `(image.png "Title")`. Reference links have none.
+func linkDestination(target string) string {
+	if !strings.HasPrefix(target, "(") {
+		return ""
+	}
+	target = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(target, "("), ")"))
+	if strings.HasPrefix(target, "<") {
+		destination, _, _ := strings.Cut(target[1:], ">")
+		return destination
+	}
+	destination, _, _ := strings.Cut(target, " ")
+	return destination
+}
+
+// htmlAttribute returns the decoded value of an attribute of a tag, and
+// whether the tag has it.
+func htmlAttribute(attributes segment, name string) (segment, bool) {
+	for _, match := range htmlAttributeRegex.FindAllStringSubmatchIndex(attributes.text, -1) {
+		if !strings.EqualFold(attributes.text[match[2]:match[3]], name) {
+			continue
+		}
+		for group := 4; group < len(match); group += 2 {
+			if match[group] != -1 {
+				return decodeEntities(attributes.slice(match[group], match[group+1])), true
+			}
+		}
+		return segment{}, true
+	}
+	return segment{}, false
+}
+
+// inlineSegments returns the alt texts of a paragraph, which are checked on
+// their own. Link texts stay in the sentences of the paragraph.
+func inlineSegments(s segment) []segment {
+	segments := []segment{}
+	for _, link := range findLinks(s) {
+		if !link.image {
+			continue
+		}
+		if label := stripInline(link.label); hasProse(label.text) {
+			segments = append(segments, label)
+		}
+	}
+	return segments
+}
+
+var (
+	imageOfRegex   = regexp.MustCompile(`(?i)^(?:an? )?(?:image|picture|photo|photograph|graphic|screenshot) of\b`)
+	vagueLinkTexts = map[string]bool{
+		"here": true, "click here": true, "click": true, "this": true, "this link": true, "link": true,
+		"read more": true, "more": true, "click this": true,
+	}
+)
+
+// linkIssue is an accessibility problem of a link or image.
+type linkIssue struct {
+	rule    string
+	message string
+	start   int
+	end     int
+}
+
+// issues runs the accessibility checks on a link or image.
+func (l inlineLink) issues() []linkIssue {
+	label := strings.TrimSpace(stripInline(l.label).text)
+	labelStart, labelEnd := l.start, l.end
+	if len(l.label.offsets) > 0 {
+		labelStart, labelEnd = l.label.offsets[0], l.label.offsets[len(l.label.offsets)-1]+1
+	}
+
+	if !l.image {
+		normalized := strings.ToLower(strings.TrimFunc(label, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsPunct(r)
+		}))
+		switch {
+		case label == "":
+			return []linkIssue{{"empty-link-text", "Link has no text. Say where it goes.", l.start, l.end}}
+		case vagueLinkTexts[normalized]:
+			return []linkIssue{{"vague-link-text", "Link text \"" + label + "\" doesn't say where the link goes.", labelStart, labelEnd}}
+		}
+		return nil
+	}
+
+	switch {
+	case !l.hasLabel || (label == "" && !l.html):
+		return []linkIssue{{"missing-alt", "Image has no alt text. Describe what it shows.", l.start, l.end}}
+	case label == "":
+		// alt="" marks a decorative HTML image
+		return nil
+	case imageOfRegex.MatchString(label):
+		return []linkIssue{{"redundant-alt", "Screen readers already announce images, so \"" + imageOfRegex.FindString(label) + "\" is redundant.", labelStart, labelEnd}}
+	case isFileName(label, l.destination):
+		return []linkIssue{{"alt-is-filename", "Alt text repeats the file name. Describe what the image shows.", labelStart, labelEnd}}
+	}
+	return nil
+}
+
+// isFileName reports whether alt text is just the file name of the image,
+// with or without its extension.
+func isFileName(alt, destination string) bool {
+	if destination == "" {
+		return false
+	}
+	normalize := func(s string) string {
+		s = strings.ToLower(s)
+		s = strings.NewReplacer("-", " ", "_", " ", ".", " ").Replace(s)
+		return strings.Join(strings.Fields(s), " ")
+	}
+
+	name := path.Base(strings.SplitN(destination, "?", 2)[0])
+	alt = normalize(alt)
+	return alt == normalize(name) || alt == normalize(strings.TrimSuffix(name, path.Ext(name)))
+}
+
+// accessibilityDiagnostics checks the links and images of a markdown
+// document. Directives and front matter can disable the checks like any
+// other category.
+func accessibilityDiagnostics(config DiagnosticConfig, text string, options parseOptions) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	doc := newDocument(text)
+	doc.exclusions = options.exclusions
+	fm := parseFrontMatter(doc)
+	if fm.disabled {
+		return diagnostics
+	}
+	root := parseMarkdown(doc.text, fm.end, options.exclusions)
+	directives := markdownDirectives(doc, root)
+	if len(fm.ignore) > 0 {
+		directives = append([]directive{{kind: directiveDisable, line: -1, categories: fm.ignore}}, directives...)
+	}
+
+	walkBlocks(root, func(b *block) {
+		for _, s := range proseSegments(doc, b) {
+			for _, link := range findLinks(s) {
+				for _, issue := range link.issues() {
+					r := Range{Start: doc.position(issue.start), End: doc.position(issue.end)}
+					if disabledCategories(directives, r)[CategoryAccessibility] {
+						continue
+					}
+					diagnostics = append(diagnostics, Diagnostic{
+						Range:           r,
+						Severity:        config.severity(CategoryAccessibility),
+						Code:            CategoryAccessibility + "/" + issue.rule,
+						CodeDescription: config.codeDescription(CategoryAccessibility),
+						Source:          "jalsa",
+						Message:         issue.message,
+					})
+				}
+			}
+		}
+	})
+
+	return diagnostics
+}
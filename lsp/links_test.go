@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestLinkSegments(t *testing.T) {
+	text := "See ![A cat sleeping on a mat](cat.png) and [the setup guide](setup.md).\n"
+	expected := []Sentence{
+		{Text: "See and the setup guide.", Range: Range{Position{0, 0}, Position{0, 72}}},
+		{Text: "A cat sleeping on a mat", Range: Range{Position{0, 6}, Position{0, 29}}},
+	}
+
+	result := parse(text)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i, sentence := range expected {
+		testSentence(t, result[i], sentence)
+	}
+}
+
+func TestLinkTextCheckedOnce(t *testing.T) {
+	result := parse("Please [click here](http://x) to continue.\n")
+	if len(result) != 1 || result[0].Text != "Please click here to continue." {
+		t.Errorf("Expected the link text in its sentence only, got %v", result)
+	}
+
+	// An image inside a link is only checked for its alt text
+	result = parse("[![The build status](badge.svg)](http://x)\n")
+	if len(result) != 1 || result[0].Text != "The build status" {
+		t.Errorf("Expected the alt text only, got %v", result)
+	}
+}
+
+func TestAccessibilityDiagnostics(t *testing.T) {
+	text := `![](chart.png) ![Image of a chart](chart.png) ![sales-chart](img/sales_chart.png)
+<img src="logo.png"> <img src="line.png" alt=""> ![Sales by region](chart.png)
+
+For details [click here](docs.md) or see [the guide](guide.md) and [](empty.md).
+
+<!-- jalsa-disable-next-line accessibility -->
+More [here](more.md).
+`
+	expected := []struct {
+		Code  string
+		Range Range
+	}{
+		{"accessibility/missing-alt", Range{Position{0, 0}, Position{0, 14}}},
+		{"accessibility/redundant-alt", Range{Position{0, 17}, Position{0, 33}}},
+		{"accessibility/alt-is-filename", Range{Position{0, 48}, Position{0, 59}}},
+		{"accessibility/missing-alt", Range{Position{1, 0}, Position{1, 20}}},
+		{"accessibility/vague-link-text", Range{Position{3, 13}, Position{3, 23}}},
+		{"accessibility/empty-link-text", Range{Position{3, 67}, Position{3, 79}}},
+	}
+
+	diagnostics := accessibilityDiagnostics(DiagnosticConfig{}, text, parseOptions{language: defaultLanguage, exclusions: exclusions})
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Code != expected[i].Code || diagnostic.Range != expected[i].Range {
+			t.Errorf("Expected %s at %v, got %s at %v", expected[i].Code, expected[i].Range, diagnostic.Code, diagnostic.Range)
+		}
+		if diagnostic.Severity != DiagnosticSeverityWarning {
+			t.Errorf("Expected severity %d, got %d", DiagnosticSeverityWarning, diagnostic.Severity)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package lsp
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+func init() {
+	registerParser("po", poSegments)
+	registerParser("pot", poSegments)
+}
+
+var (
+	poKeywordRegex  = regexp.MustCompile(`^(msgctxt|msgid|msgid_plural|msgstr(?:\[\d+\])?)[ \t]+"`)
+	poLanguageRegex = regexp.MustCompile(`(?m)^"Language:[ \t]*([A-Za-z]{2,3}(?:[-_][A-Za-z0-9]+)*)`)
+	localeTagRegex  = regexp.MustCompile(`^[a-z]{2,3}(?:[-_](?:[A-Z][a-z]{3}|[A-Z]{2}|\d{3}))*$`)
+	localeKeyRegex  = regexp.MustCompile(`^(?:"[^"]*"|'[^']*'|[^\s#:'"{\[&*!|>-][^:#]*?)[ \t]*:(?:[ \t]+|$)`)
+	yamlSkipRegex   = regexp.MustCompile(`^(?:true|false|yes|no|on|off|null|~|[&*!].*)$`)
+
+	// localePlaceholderRegex matches printf verbs like %s and %1$d, Python's
+	// %(name)s and the variables of i18n libraries, like {name}, {{count}},
+	// %{count} and ${name}.
+	localePlaceholderRegex = regexp.MustCompile(`%(?:\d+\$)?[-+# 0]*\d*(?:\.\d+)?[a-zA-Z@]|%\(\w+\)[a-z]|%\{\w+\}|\$\{\w+\}|\{\{[^{}]*\}\}|\{\w+(?:\.\w+)*\}`)
+)
+
+// localeDirectories hold the locale bundles of a project.
+var localeDirectories = []string{"i18n", "l10n", "lang", "langs", "languages", "locale", "locales", "messages", "translations"}
+
+// languageCodes are the ISO 639-1 codes, and the three letter codes of
+// languages without one that apps are often translated to.
+var languageCodes = strings.Fields(`
+	aa ab ae af ak am an ar as av ay az ba be bg bh bi bm bn bo br bs ca ce ch co cr cs cu cv cy da de dv dz
+	ee el en eo es et eu fa ff fi fj fo fr fy ga gd gl gn gu gv ha he hi ho hr ht hu hy hz ia id ie ig ii ik
+	io is it iu ja jv ka kg ki kj kk kl km kn ko kr ks ku kv kw ky la lb lg li ln lo lt lu lv mg mh mi mk ml
+	mn mr ms mt my na nb nd ne ng nl nn no nr nv ny oc oj om or os pa pi pl ps pt qu rm rn ro ru rw sa sc sd
+	se sg si sk sl sm sn so sq sr ss st su sv sw ta te tg th ti tk tl tn to tr ts tt tw ty ug uk ur uz ve vi
+	vo wa wo xh yi yo za zh zu ast ckb fil haw yue`)
+
+// isLocale reports whether tag is a locale like "fr" or "pt-BR" of a known
+// language.
+func isLocale(tag string) bool {
+	language, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return localeTagRegex.MatchString(tag) && contains(languageCodes, language)
+}
+
+// localeOf returns the locale of a JSON or YAML locale bundle, like "fr" for
+// locales/fr.json, i18n/fr/common.json or translations/messages.fr.yaml, or
+// "" for files outside of a locale directory. The name of the file is
+// preferred over the directories below the locale directory.
+func localeOf(fileURI string) string {
+	dirs := strings.Split(path.Dir(strings.TrimPrefix(fileURI, "file://")), "/")
+	for i, dir := range dirs {
+		if !contains(localeDirectories, dir) {
+			continue
+		}
+		parts := strings.Split(path.Base(fileURI), ".")
+		for j := len(parts) - 2; j >= 0; j-- {
+			if isLocale(parts[j]) {
+				return parts[j]
+			}
+		}
+		for k := len(dirs) - 1; k > i; k-- {
+			if isLocale(dirs[k]) {
+				return dirs[k]
+			}
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// localeSegments extracts the strings of a JSON or YAML locale bundle in
+// language.
+func localeSegments(languageID, language string) func(doc *document) []segment {
+	return func(doc *document) []segment {
+		if languageID == "yaml" {
+			return yamlLocaleSegments(doc, language)
+		}
+		return jsonLocaleSegments(doc, language)
+	}
+}
+
+// localeString returns s with its placeholders protected.
+func localeString(s segment, language string) segment {
+	s = excludeInline(s, []Exclusion{{Name: "placeholders", Inline: localePlaceholderRegex}})
+	s.language = language
+	return s
+}
+
+// poSegments extracts the strings of a gettext catalog. Source strings are in
+// the language of the document, and translations in the language of the
+// header. Contexts, comments, obsolete entries and the header are skipped.
+func poSegments(doc *document) []segment {
+	language := ""
+	if match := poLanguageRegex.FindStringSubmatch(doc.text); match != nil {
+		language = match[1]
+	}
+
+	segments := []segment{}
+	keyword := ""
+	current := segment{}
+	header := false
+	flush := func() {
+		add := func(tag string) {
+			if hasProse(current.text) {
+				segments = append(segments, localeString(current, tag))
+			}
+		}
+		switch {
+		case keyword == "msgid":
+			header = current.text == ""
+			add("")
+		case keyword == "msgid_plural":
+			add("")
+		case strings.HasPrefix(keyword, "msgstr") && !header:
+			add(language)
+		}
+		keyword = ""
+		current = segment{}
+	}
+
+	for i := range doc.lineStarts {
+		line := doc.lineSpan(i)
+		text := strings.TrimRight(doc.text[line.start:line.end], " \t")
+		end := line.start + len(text)
+		if match := poKeywordRegex.FindStringSubmatch(text); match != nil {
+			flush()
+			keyword = match[1]
+			current.append(quotedValue(doc, line.start+len(match[0])-1, end))
+			continue
+		}
+		if keyword != "" && strings.HasPrefix(text, `"`) {
+			// Strings on the following lines are concatenated
+			current.append(quotedValue(doc, line.start, end))
+			continue
+		}
+		flush()
+	}
+	flush()
+
+	return segments
+}
+
+// jsonLocaleSegments extracts the string values of a JSON locale bundle,
+// wherever they are nested. Keys are skipped.
+func jsonLocaleSegments(doc *document, language string) []segment {
+	segments := []segment{}
+	text := doc.text
+	for i := 0; i < len(text); i++ {
+		if text[i] != '"' {
+			continue
+		}
+		start := i
+		for i++; i < len(text) && text[i] != '"'; i++ {
+			if text[i] == '\\' {
+				i++
+			}
+		}
+		end := min(i+1, len(text))
+
+		next := end
+		for next < len(text) && strings.IndexByte(" \t\r\n", text[next]) != -1 {
+			next++
+		}
+		if next < len(text) && text[next] == ':' {
+			continue
+		}
+		if s := jsonString(doc, start, end); hasProse(s.text) {
+			segments = append(segments, localeString(s, language))
+		}
+	}
+	return segments
+}
+
+// jsonString returns the value of the JSON string between start and end.
+// Every character of an escape sequence maps to the whole sequence.
+func jsonString(doc *document, start, end int) segment {
+	s := segment{}
+	last := end - 1
+	for i := start + 1; i < last; {
+		if doc.text[i] != '\\' || i+1 >= last {
+			s.add(doc, i, i+1)
+			i++
+			continue
+		}
+		switch c := doc.text[i+1]; c {
+		case 'u':
+			r, size := jsonRune(doc.text[i:last])
+			if size == 0 {
+				s.add(doc, i, i+2)
+				i += 2
+				continue
+			}
+			s.replace(string(r), i, i+size)
+			i += size
+		case 'n', 'r', 't', 'b', 'f':
+			s.replace(" ", i, i+2)
+			i += 2
+		default:
+			s.replace(string(c), i, i+2)
+			i += 2
+		}
+	}
+	return s
+}
+
+// jsonRune decodes a \uXXXX escape, or a pair of them for a surrogate pair,
+// and returns its length.
+func jsonRune(text string) (rune, int) {
+	hex := func(text string) rune {
+		if len(text) < 6 || !strings.HasPrefix(text, `\u`) {
+			return -1
+		}
+		value, err := strconv.ParseUint(text[2:6], 16, 16)
+		if err != nil {
+			return -1
+		}
+		return rune(value)
+	}
+
+	r := hex(text)
+	if r == -1 {
+		return 0, 0
+	}
+	if utf16.IsSurrogate(r) {
+		if low := hex(text[6:]); low != -1 {
+			return utf16.DecodeRune(r, low), 12
+		}
+	}
+	return r, 6
+}
+
+// yamlLocaleSegments extracts the scalar values of a YAML locale bundle,
+// wherever they are nested. Keys, booleans, anchors and aliases are skipped.
+func yamlLocaleSegments(doc *document, language string) []segment {
+	segments := []segment{}
+	indentOf := func(text string) int {
+		return len(text) - len(strings.TrimLeft(text, " "))
+	}
+
+	for line := 0; line < len(doc.lineStarts); {
+		s := doc.lineSpan(line)
+		text := doc.text[s.start:s.end]
+		line++
+		indent := indentOf(text)
+		start := s.start + indent
+		content := text[indent:]
+
+		// Items of lists, which may be mappings too
+		for strings.HasPrefix(content, "- ") {
+			start += 2
+			content = content[2:]
+		}
+		if key := localeKeyRegex.FindString(content); key != "" {
+			start += len(key)
+			content = content[len(key):]
+		} else if start == s.start+indent {
+			continue
+		}
+
+		value := strings.TrimRight(stripYAMLComment(content), " \t\r")
+		if value == "" || yamlSkipRegex.MatchString(value) || value[0] == '{' || value[0] == '[' {
+			continue
+		}
+
+		// Block scalars and plain scalars go on over more indented lines
+		nested := []span{}
+		for ; line < len(doc.lineStarts); line++ {
+			next := doc.lineSpan(line)
+			content := doc.text[next.start:next.end]
+			if strings.TrimSpace(content) != "" && indentOf(content) <= indent {
+				break
+			}
+			nested = append(nested, next)
+		}
+		v := yamlValue(doc, start, start+len(value), nested).text
+		if hasProse(v.text) {
+			segments = append(segments, localeString(v, language))
+		}
+	}
+	return segments
+}
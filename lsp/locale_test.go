@@ -0,0 +1,100 @@
+package lsp
+
+import "testing"
+
+func TestLocaleSegments(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Text     string
+		Extract  func(doc *document) []segment
+		Expected []Sentence
+		Language []string
+	}{
+		{
+			Name:    "po",
+			Text:    "msgid \"\"\nmsgstr \"\"\n\"Language: de\\n\"\n\n#: app.c:3\nmsgctxt \"menu\"\nmsgid \"Open %s file.\"\nmsgstr \"Öffne die Datei %s.\"\n\nmsgid \"\"\n\"You have {{count}} new \"\n\"messages.\"\nmsgid_plural \"You have one message.\"\nmsgstr[0] \"Du hast eine Nachricht.\"\n\n#~ msgid \"Old text.\"\n",
+			Extract: poSegments,
+			Expected: []Sentence{
+				{Text: "Open ⟦code⟧ file.", Range: Range{Position{6, 7}, Position{6, 20}}},
+				{Text: "Öffne die Datei ⟦code⟧.", Range: Range{Position{7, 8}, Position{7, 28}}},
+				{Text: "You have ⟦code⟧ new messages.", Range: Range{Position{10, 1}, Position{11, 10}}},
+				{Text: "You have one message.", Range: Range{Position{12, 14}, Position{12, 35}}},
+				{Text: "Du hast eine Nachricht.", Range: Range{Position{13, 11}, Position{13, 34}}},
+			},
+			Language: []string{"", "de", "", "", "de"},
+		},
+		{
+			Name:    "json",
+			Text:    "{\n  \"greeting\": {\n    \"hello\": \"Hello, {name}!\",\n    \"escaped\": \"Caf\\u00e9 \\\"open\\\" now.\"\n  },\n  \"count\": 3,\n  \"items\": [\"First item.\", \"Second %1$d item.\"]\n}\n",
+			Extract: localeSegments("json", "fr"),
+			Expected: []Sentence{
+				{Text: "Hello, ⟦code⟧!", Range: Range{Position{2, 14}, Position{2, 28}}},
+				{Text: "Café \"open\" now.", Range: Range{Position{3, 16}, Position{3, 39}}},
+				{Text: "First item.", Range: Range{Position{6, 13}, Position{6, 24}}},
+				{Text: "Second ⟦code⟧ item.", Range: Range{Position{6, 28}, Position{6, 45}}},
+			},
+			Language: []string{"fr", "fr", "fr", "fr"},
+		},
+		{
+			Name:    "yaml",
+			Text:    "en:\n  greeting:\n    hello: Hello, %{name}!\n    long: >\n      This text spans\n      two lines.\n  enabled: true\n  quoted: \"It's 'quoted'.\"\n  list:\n    - First item.\n    - label: Second item.\n",
+			Extract: localeSegments("yaml", "en-GB"),
+			Expected: []Sentence{
+				{Text: "Hello, ⟦code⟧!", Range: Range{Position{2, 11}, Position{2, 26}}},
+				{Text: "This text spans two lines.", Range: Range{Position{4, 6}, Position{5, 16}}},
+				{Text: "It's 'quoted'.", Range: Range{Position{7, 11}, Position{7, 25}}},
+				{Text: "First item.", Range: Range{Position{9, 6}, Position{9, 17}}},
+				{Text: "Second item.", Range: Range{Position{10, 13}, Position{10, 25}}},
+			},
+			Language: []string{"en-GB", "en-GB", "en-GB", "en-GB", "en-GB"},
+		},
+	}
+
+	for _, test := range tests {
+		result := parseSegments(test.Text, parseOptions{language: defaultLanguage}, test.Extract)
+		if len(result) != len(test.Expected) {
+			t.Errorf("%s: Expected %d sentences, got %d: %v", test.Name, len(test.Expected), len(result), result)
+			continue
+		}
+		for i := range test.Expected {
+			testSentence(t, result[i], test.Expected[i])
+			if result[i].language != test.Language[i] {
+				t.Errorf("%s: Expected language %q, got %q", test.Name, test.Language[i], result[i].language)
+			}
+		}
+	}
+}
+
+func TestLocaleOf(t *testing.T) {
+	tests := []struct {
+		URI      string
+		Expected string
+	}{
+		{"file:///project/locales/fr.json", "fr"},
+		{"file:///project/i18n/pt-BR/common.json", "pt-BR"},
+		{"file:///project/translations/messages.de.yaml", "de"},
+		{"file:///project/config/locales/en_US.yml", "en_US"},
+		{"file:///project/config/app.json", ""},
+		{"file:///project/locales/index.json", ""},
+		{"file:///project/locales/new.json", ""},
+		{"file:///project/i18n/web/en.json", "en"},
+		{"file:///project/i18n/web/common.json", ""},
+	}
+
+	for _, test := range tests {
+		if result := localeOf(test.URI); result != test.Expected {
+			t.Errorf("Expected %q for %s, got %q", test.Expected, test.URI, result)
+		}
+	}
+}
+
+func TestSentenceHash(t *testing.T) {
+	english := Sentence{Text: "Test."}
+	german := Sentence{Text: "Test.", language: "de"}
+	if english.hash() != hash("Test.") {
+		t.Errorf("Expected sentences in the default language to keep their hash")
+	}
+	if english.hash() == german.hash() {
+		t.Errorf("Expected sentences in different languages to have different hashes")
+	}
+}
@@ -0,0 +1,719 @@
+package lsp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This is a CommonMark block parser modelled on the reference implementation
+// (commonmark.js). It only builds the block structure with source offsets;
+// inline content is kept as spans of the original text.
+
+type blockKind int
+
+const (
+	blockDocument blockKind = iota
+	blockQuote
+	blockList
+	blockItem
+	blockParagraph
+	blockHeading
+	blockFencedCode
+	blockIndentedCode
+	blockHTML
+	blockThematicBreak
+	blockTable
+	// blockExcluded and blockContainer are recognized by an Exclusion
+	blockExcluded
+	blockContainer
+)
+
+// span is a range of byte offsets into the document.
+type span struct {
+	start int
+	end   int
+}
+
+type block struct {
+	kind     blockKind
+	parent   *block
+	children []*block
+	open     bool
+
+	// lines holds the content of paragraphs and headings, the rows of tables
+	// and the raw lines of HTML blocks, without container markers or
+	// indentation.
+	lines []span
+
+	// startLine and endLine are the first and last document lines of the block
+	startLine int
+	endLine   int
+
+	level int
+
+	fenceChar   byte
+	fenceLength int
+	fenceOffset int
+
+	htmlType int
+
+	exclusion *Exclusion
+
+	ordered      bool
+	bulletChar   byte
+	delimiter    byte
+	start        int
+	markerOffset int
+	padding      int
+}
+
+func (b *block) lastChild() *block {
+	if len(b.children) == 0 {
+		return nil
+	}
+	return b.children[len(b.children)-1]
+}
+
+func canContain(parent, child blockKind) bool {
+	switch parent {
+	case blockDocument, blockQuote, blockItem, blockContainer:
+		return child != blockItem
+	case blockList:
+		return child == blockItem
+	}
+	return false
+}
+
+func acceptsLines(kind blockKind) bool {
+	switch kind {
+	case blockParagraph, blockFencedCode, blockIndentedCode, blockHTML, blockTable, blockExcluded:
+		return true
+	}
+	return false
+}
+
+type blockParser struct {
+	text       string
+	doc        *block
+	tip        *block
+	exclusions []Exclusion
+
+	oldTip               *block
+	lastMatchedContainer *block
+	allClosed            bool
+
+	line       string
+	lineStart  int
+	lineNumber int
+
+	offset             int
+	column             int
+	nextNonspace       int
+	nextNonspaceColumn int
+	indent             int
+	indented           bool
+	blank              bool
+}
+
+var (
+	atxHeadingRegex     = regexp.MustCompile(`^#{1,6}(?:[ \t]+|$)`)
+	closingFenceRegex   = regexp.MustCompile(`^(?:` + "`{3,}" + `|~{3,})[ \t]*$`)
+	codeFenceRegex      = regexp.MustCompile("^`{3,}(?:[^`]*)$|^~{3,}")
+	setextHeadingRegex  = regexp.MustCompile(`^(?:=+|-+)[ \t]*$`)
+	thematicBreakRegex  = regexp.MustCompile(`^(?:\*[ \t]*){3,}$|^(?:_[ \t]*){3,}$|^(?:-[ \t]*){3,}$`)
+	bulletMarkerRegex   = regexp.MustCompile(`^[*+-]`)
+	orderedMarkerRegex  = regexp.MustCompile(`^(\d{1,9})([.)])`)
+	linkReferenceRegex  = regexp.MustCompile(`^ {0,3}\[(?:[^\]\\]|\\.)+\]:[ \t]*\S+`)
+	atxClosingRegex     = regexp.MustCompile(`(?:^|[ \t]+)#+[ \t]*$`)
+	tableDelimiterRegex = regexp.MustCompile(`^\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	htmlBlockStartRegex = []*regexp.Regexp{
+		nil,
+		regexp.MustCompile(`(?i)^<(?:script|pre|textarea|style)(?:\s|>|$)`),
+		regexp.MustCompile(`^<!--`),
+		regexp.MustCompile(`^<[?]`),
+		regexp.MustCompile(`^<![A-Za-z]`),
+		regexp.MustCompile(`^<!\[CDATA\[`),
+		regexp.MustCompile(`(?i)^</?(?:address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[123456]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(?:\s|/?>|$)`),
+		regexp.MustCompile(`(?i)^(?:<[A-Za-z][A-Za-z0-9-]*(?:\s+[a-zA-Z_:][a-zA-Z0-9_.:-]*(?:\s*=\s*(?:[^"'=<>` + "`" + `\x00-\x20]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>)\s*$`),
+	}
+	htmlBlockEndRegex = []*regexp.Regexp{
+		nil,
+		regexp.MustCompile(`(?i)</(?:script|pre|textarea|style)>`),
+		regexp.MustCompile(`-->`),
+		regexp.MustCompile(`\?>`),
+		regexp.MustCompile(`>`),
+		regexp.MustCompile(`\]\]>`),
+	}
+)
+
+// parseMarkdown returns the block tree of a markdown document. Parsing starts
+// at offset so that front matter can be skipped. Blocks recognized by the
+// exclusions are kept out of the prose blocks.
+func parseMarkdown(text string, offset int, exclusions []Exclusion) *block {
+	doc := &block{kind: blockDocument, open: true}
+	p := &blockParser{text: text, doc: doc, tip: doc, oldTip: doc, lastMatchedContainer: doc, exclusions: exclusions}
+
+	lineStart := offset
+	p.lineNumber = strings.Count(text[:offset], "\n")
+	for lineStart <= len(text) {
+		lineEnd := strings.IndexByte(text[lineStart:], '\n')
+		if lineEnd == -1 {
+			lineEnd = len(text)
+		} else {
+			lineEnd += lineStart
+		}
+
+		if lineStart < len(text) || lineStart == offset {
+			p.incorporateLine(lineStart, strings.TrimSuffix(text[lineStart:lineEnd], "\r"))
+		}
+
+		lineStart = lineEnd + 1
+		p.lineNumber++
+	}
+
+	for p.tip != nil {
+		p.finalize(p.tip)
+	}
+
+	return doc
+}
+
+func (p *blockParser) peek() byte {
+	if p.offset < len(p.line) {
+		return p.line[p.offset]
+	}
+	return 0
+}
+
+func (p *blockParser) findNextNonspace() {
+	i := p.offset
+	column := p.column
+
+	for i < len(p.line) {
+		if p.line[i] == ' ' {
+			i++
+			column++
+		} else if p.line[i] == '\t' {
+			i++
+			column += 4 - column%4
+		} else {
+			break
+		}
+	}
+
+	p.blank = i == len(p.line)
+	p.nextNonspace = i
+	p.nextNonspaceColumn = column
+	p.indent = column - p.column
+	p.indented = p.indent >= 4
+}
+
+func (p *blockParser) advanceNextNonspace() {
+	p.offset = p.nextNonspace
+	p.column = p.nextNonspaceColumn
+}
+
+// advanceOffset moves forward by count bytes, or by count columns when
+// columns is set. A tab is always consumed whole.
+func (p *blockParser) advanceOffset(count int, columns bool) {
+	for count > 0 && p.offset < len(p.line) {
+		if p.line[p.offset] == '\t' {
+			width := 4 - p.column%4
+			p.column += width
+			if columns {
+				count -= width
+			} else {
+				count--
+			}
+		} else {
+			p.column++
+			count--
+		}
+		p.offset++
+	}
+}
+
+func (p *blockParser) rest() string {
+	return p.line[p.nextNonspace:]
+}
+
+func (p *blockParser) addLine() {
+	switch p.tip.kind {
+	case blockParagraph, blockHTML, blockTable:
+		start := p.offset
+		if p.tip.kind != blockHTML {
+			for start < len(p.line) && (p.line[start] == ' ' || p.line[start] == '\t') {
+				start++
+			}
+		}
+		p.tip.lines = append(p.tip.lines, span{p.lineStart + start, p.lineStart + len(p.line)})
+	}
+	p.tip.endLine = p.lineNumber
+}
+
+func (p *blockParser) addChild(kind blockKind) *block {
+	for !canContain(p.tip.kind, kind) {
+		p.finalize(p.tip)
+	}
+
+	child := &block{kind: kind, parent: p.tip, open: true, startLine: p.lineNumber, endLine: p.lineNumber}
+	p.tip.children = append(p.tip.children, child)
+	p.tip = child
+	return child
+}
+
+func (p *blockParser) closeUnmatchedBlocks() {
+	if p.allClosed {
+		return
+	}
+	for p.oldTip != p.lastMatchedContainer {
+		parent := p.oldTip.parent
+		p.finalize(p.oldTip)
+		p.oldTip = parent
+	}
+	p.allClosed = true
+}
+
+func (p *blockParser) finalize(b *block) {
+	b.open = false
+	if last := b.lastChild(); last != nil {
+		b.endLine = max(b.endLine, last.endLine)
+	}
+
+	if b.kind == blockParagraph {
+		// Link reference definitions aren't prose
+		for len(b.lines) > 0 && linkReferenceRegex.MatchString(p.text[b.lines[0].start:b.lines[0].end]) {
+			b.lines = b.lines[1:]
+		}
+	}
+
+	p.tip = b.parent
+}
+
+// continueBlock reports whether an open block matches the current line: 0
+// when it does, 1 when it doesn't and 2 when the line closed the block.
+func (p *blockParser) continueBlock(b *block) int {
+	switch b.kind {
+	case blockQuote:
+		if !p.indented && !p.blank && p.line[p.nextNonspace] == '>' {
+			p.advanceNextNonspace()
+			p.advanceOffset(1, false)
+			if c := p.peek(); c == ' ' || c == '\t' {
+				p.advanceOffset(1, true)
+			}
+			return 0
+		}
+		return 1
+	case blockItem:
+		if p.blank {
+			if len(b.children) == 0 {
+				return 1
+			}
+			p.advanceNextNonspace()
+			return 0
+		}
+		if p.indent >= b.markerOffset+b.padding {
+			p.advanceOffset(b.markerOffset+b.padding, true)
+			return 0
+		}
+		return 1
+	case blockHeading, blockThematicBreak:
+		return 1
+	case blockFencedCode:
+		rest := p.rest()
+		if p.indent <= 3 && len(rest) > 0 && rest[0] == b.fenceChar && closingFenceRegex.MatchString(rest) {
+			length := len(rest) - len(strings.TrimLeft(rest, string(b.fenceChar)))
+			if length >= b.fenceLength {
+				b.endLine = p.lineNumber
+				p.finalize(b)
+				return 2
+			}
+		}
+		for i := b.fenceOffset; i > 0 && p.peek() == ' '; i-- {
+			p.advanceOffset(1, true)
+		}
+		return 0
+	case blockIndentedCode:
+		if p.indent >= 4 {
+			p.advanceOffset(4, true)
+			return 0
+		}
+		if p.blank {
+			p.advanceNextNonspace()
+			return 0
+		}
+		return 1
+	case blockHTML:
+		if p.blank && (b.htmlType == 6 || b.htmlType == 7) {
+			return 1
+		}
+		return 0
+	case blockParagraph, blockTable:
+		if p.blank {
+			return 1
+		}
+		return 0
+	case blockContainer:
+		if p.blank {
+			p.advanceNextNonspace()
+			return 0
+		}
+		if p.indent >= 4 {
+			p.advanceOffset(4, true)
+			return 0
+		}
+		return 1
+	case blockExcluded:
+		return 0
+	}
+	return 0
+}
+
+// startBlock tries to open a new block at the current position: 0 when none
+// starts, 1 when a container starts and 2 when a leaf starts.
+func (p *blockParser) startBlock(container *block) int {
+	rest := p.rest()
+	if p.indented {
+		if p.tip.kind != blockParagraph && !p.blank {
+			p.advanceOffset(4, true)
+			p.closeUnmatchedBlocks()
+			p.addChild(blockIndentedCode)
+			return 2
+		}
+		return 0
+	}
+
+	for i := range p.exclusions {
+		exclusion := &p.exclusions[i]
+		if exclusion.Start == nil {
+			continue
+		}
+		match := exclusion.Start.FindStringIndex(rest)
+		// Containers don't interrupt a paragraph
+		if match == nil || (exclusion.Container && container.kind == blockParagraph) {
+			continue
+		}
+
+		p.closeUnmatchedBlocks()
+		if exclusion.Container {
+			p.advanceNextNonspace()
+			p.advanceOffset(match[1], false)
+			p.addChild(blockContainer).exclusion = exclusion
+			return 1
+		}
+
+		excluded := p.addChild(blockExcluded)
+		excluded.exclusion = exclusion
+		if exclusion.End == nil || exclusion.End.MatchString(rest[match[1]:]) {
+			p.finalize(excluded)
+		}
+		p.advanceOffset(len(p.line)-p.offset, false)
+		return 2
+	}
+
+	switch {
+	case strings.HasPrefix(rest, ">"):
+		p.advanceNextNonspace()
+		p.advanceOffset(1, false)
+		if c := p.peek(); c == ' ' || c == '\t' {
+			p.advanceOffset(1, true)
+		}
+		p.closeUnmatchedBlocks()
+		p.addChild(blockQuote)
+		return 1
+
+	case atxHeadingRegex.MatchString(rest):
+		p.advanceNextNonspace()
+		marker := atxHeadingRegex.FindString(rest)
+		p.advanceOffset(len(marker), false)
+		p.closeUnmatchedBlocks()
+		heading := p.addChild(blockHeading)
+		heading.level = len(strings.TrimRight(marker, " \t"))
+
+		content := p.line[p.offset:]
+		if loc := atxClosingRegex.FindStringIndex(content); loc != nil {
+			content = content[:loc[0]]
+		}
+		content = strings.TrimRight(content, " \t")
+		if content != "" {
+			start := p.lineStart + p.offset
+			heading.lines = []span{{start, start + len(content)}}
+		}
+		p.advanceOffset(len(p.line)-p.offset, false)
+		return 2
+
+	case codeFenceRegex.MatchString(rest):
+		fence := rest[:len(rest)-len(strings.TrimLeft(rest, rest[:1]))]
+		p.closeUnmatchedBlocks()
+		code := p.addChild(blockFencedCode)
+		code.fenceChar = fence[0]
+		code.fenceLength = len(fence)
+		code.fenceOffset = p.indent
+		p.advanceNextNonspace()
+		p.advanceOffset(len(p.line)-p.offset, false)
+		return 2
+
+	case strings.HasPrefix(rest, "<"):
+		for htmlType := 1; htmlType <= 7; htmlType++ {
+			if !htmlBlockStartRegex[htmlType].MatchString(rest) {
+				continue
+			}
+			if htmlType == 7 && (container.kind == blockParagraph || (!p.allClosed && !p.blank && p.tip.kind == blockParagraph)) {
+				break
+			}
+			p.closeUnmatchedBlocks()
+			html := p.addChild(blockHTML)
+			html.htmlType = htmlType
+			return 2
+		}
+	}
+
+	if container.kind == blockParagraph && p.startTable(container) {
+		return 2
+	}
+
+	if container.kind == blockParagraph && setextHeadingRegex.MatchString(rest) && len(container.lines) > 0 {
+		p.closeUnmatchedBlocks()
+		container.kind = blockHeading
+		container.level = 1
+		if rest[0] == '-' {
+			container.level = 2
+		}
+		container.endLine = p.lineNumber
+		p.advanceOffset(len(p.line)-p.offset, false)
+		return 2
+	}
+
+	if thematicBreakRegex.MatchString(rest) {
+		p.closeUnmatchedBlocks()
+		p.addChild(blockThematicBreak)
+		p.advanceOffset(len(p.line)-p.offset, false)
+		return 2
+	}
+
+	if p.startListItem(container) {
+		return 1
+	}
+
+	return 0
+}
+
+// startTable turns the last line of a paragraph into the header row of a GFM
+// table when the current line is a delimiter row with as many cells.
+func (p *blockParser) startTable(paragraph *block) bool {
+	rest := p.rest()
+	if len(paragraph.lines) == 0 || !strings.Contains(rest, "|") || !tableDelimiterRegex.MatchString(rest) {
+		return false
+	}
+	header := paragraph.lines[len(paragraph.lines)-1]
+	if !strings.Contains(p.text[header.start:header.end], "|") ||
+		len(tableCells(p.text, header)) != len(tableCells(p.text, span{p.lineStart + p.nextNonspace, p.lineStart + len(p.line)})) {
+		return false
+	}
+
+	p.closeUnmatchedBlocks()
+	if len(paragraph.lines) == 1 {
+		paragraph.kind = blockTable
+	} else {
+		paragraph.lines = paragraph.lines[:len(paragraph.lines)-1]
+		paragraph.endLine = p.lineNumber - 2
+		p.finalize(paragraph)
+		table := p.addChild(blockTable)
+		table.startLine = p.lineNumber - 1
+		table.lines = []span{header}
+	}
+	p.advanceNextNonspace()
+	return true
+}
+
+// tableCells splits a table row into the spans of its cells, without the
+// surrounding spaces. Escaped pipes don't separate cells.
+func tableCells(text string, row span) []span {
+	line := text[row.start:row.end]
+	start := 0
+	if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "|") {
+		start = len(line) - len(trimmed) + 1
+	}
+
+	cells := []span{}
+	for i := start; i <= len(line); i++ {
+		if i < len(line) && line[i] == '\\' {
+			i++
+			continue
+		}
+		if i < len(line) && line[i] != '|' {
+			continue
+		}
+		cell := span{row.start + start, row.start + i}
+		if i == len(line) && strings.TrimSpace(line[start:i]) == "" && len(cells) > 0 {
+			break
+		}
+		for cell.start < cell.end && (text[cell.start] == ' ' || text[cell.start] == '\t') {
+			cell.start++
+		}
+		for cell.end > cell.start && (text[cell.end-1] == ' ' || text[cell.end-1] == '\t') {
+			cell.end--
+		}
+		cells = append(cells, cell)
+		start = i + 1
+	}
+	return cells
+}
+
+// startListItem opens a list item, and a list around it when needed, if the
+// line starts with a list marker.
+func (p *blockParser) startListItem(container *block) bool {
+	if p.indent >= 4 {
+		return false
+	}
+
+	item := &block{kind: blockItem}
+	rest := p.rest()
+	markerLength := 0
+
+	if match := bulletMarkerRegex.FindString(rest); match != "" {
+		item.bulletChar = match[0]
+		markerLength = 1
+	} else if match := orderedMarkerRegex.FindStringSubmatch(rest); match != nil &&
+		(container.kind != blockParagraph || match[1] == "1") {
+		item.ordered = true
+		item.start, _ = strconv.Atoi(match[1])
+		item.delimiter = match[2][0]
+		markerLength = len(match[0])
+	} else {
+		return false
+	}
+
+	if markerLength < len(rest) && rest[markerLength] != ' ' && rest[markerLength] != '\t' {
+		return false
+	}
+	if container.kind == blockParagraph && strings.TrimSpace(rest[markerLength:]) == "" {
+		return false
+	}
+
+	item.markerOffset = p.indent
+	p.advanceNextNonspace()
+	p.advanceOffset(markerLength, true)
+
+	spacesStartColumn := p.column
+	spacesStartOffset := p.offset
+	for p.column-spacesStartColumn < 5 && (p.peek() == ' ' || p.peek() == '\t') {
+		p.advanceOffset(1, true)
+	}
+
+	blankItem := p.offset == len(p.line)
+	spacesAfterMarker := p.column - spacesStartColumn
+	if spacesAfterMarker >= 5 || spacesAfterMarker < 1 || blankItem {
+		item.padding = markerLength + 1
+		p.column = spacesStartColumn
+		p.offset = spacesStartOffset
+		if c := p.peek(); c == ' ' || c == '\t' {
+			p.advanceOffset(1, true)
+		}
+	} else {
+		item.padding = markerLength + spacesAfterMarker
+	}
+
+	p.closeUnmatchedBlocks()
+	if p.tip.kind != blockList || !listsMatch(p.tip, item) {
+		list := p.addChild(blockList)
+		list.ordered = item.ordered
+		list.bulletChar = item.bulletChar
+		list.delimiter = item.delimiter
+		list.start = item.start
+	}
+
+	child := p.addChild(blockItem)
+	child.ordered = item.ordered
+	child.bulletChar = item.bulletChar
+	child.delimiter = item.delimiter
+	child.start = item.start
+	child.markerOffset = item.markerOffset
+	child.padding = item.padding
+	return true
+}
+
+func listsMatch(list, item *block) bool {
+	return list.ordered == item.ordered && list.bulletChar == item.bulletChar && list.delimiter == item.delimiter
+}
+
+func (p *blockParser) incorporateLine(lineStart int, line string) {
+	p.line = line
+	p.lineStart = lineStart
+	p.offset = 0
+	p.column = 0
+	p.blank = false
+	p.oldTip = p.tip
+
+	container := p.doc
+	allMatched := true
+	for {
+		last := container.lastChild()
+		if last == nil || !last.open {
+			break
+		}
+		container = last
+
+		p.findNextNonspace()
+		switch p.continueBlock(container) {
+		case 1:
+			container = container.parent
+			allMatched = false
+		case 2:
+			return
+		}
+		if !allMatched {
+			break
+		}
+	}
+
+	p.allClosed = container == p.oldTip
+	p.lastMatchedContainer = container
+
+	matchedLeaf := container.kind != blockParagraph && container.kind != blockTable && acceptsLines(container.kind)
+	for !matchedLeaf {
+		p.findNextNonspace()
+		started := p.startBlock(container)
+		if started == 0 {
+			p.advanceNextNonspace()
+			break
+		}
+		container = p.tip
+		if started == 2 {
+			break
+		}
+	}
+
+	if !p.allClosed && !p.blank && p.tip.kind == blockParagraph {
+		// Lazy paragraph continuation
+		p.addLine()
+		return
+	}
+
+	p.closeUnmatchedBlocks()
+
+	switch {
+	case acceptsLines(container.kind):
+		p.addLine()
+		if container.kind == blockHTML && container.htmlType >= 1 && container.htmlType <= 5 &&
+			htmlBlockEndRegex[container.htmlType].MatchString(p.line[p.offset:]) {
+			p.finalize(container)
+		}
+		if container.kind == blockExcluded && container.exclusion.End.MatchString(p.line[p.offset:]) {
+			p.finalize(container)
+		}
+	case p.offset < len(p.line) && !p.blank:
+		p.addChild(blockParagraph)
+		p.advanceNextNonspace()
+		p.addLine()
+	}
+}
+
+// walkBlocks calls fn for every block in document order.
+func walkBlocks(b *block, fn func(*block)) {
+	fn(b)
+	for _, child := range b.children {
+		walkBlocks(child, fn)
+	}
+}
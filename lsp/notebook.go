@@ -0,0 +1,221 @@
+package lsp
+
+const (
+	NotebookCellKindMarkup = 1
+	NotebookCellKindCode   = 2
+)
+
+type NotebookDocumentSyncOptions struct {
+	NotebookSelector []NotebookSelector `json:"notebookSelector"`
+	Save             bool               `json:"save"`
+}
+
+type NotebookSelector struct {
+	Notebook NotebookDocumentFilter `json:"notebook"`
+}
+
+type NotebookDocumentFilter struct {
+	NotebookType string `json:"notebookType"`
+}
+
+type NotebookDocument struct {
+	URI          string         `json:"uri"`
+	NotebookType string         `json:"notebookType"`
+	Version      int            `json:"version"`
+	Cells        []NotebookCell `json:"cells"`
+}
+
+type NotebookCell struct {
+	Kind     int    `json:"kind"`
+	Document string `json:"document"`
+}
+
+type NotebookDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type DidOpenNotebookDocumentNotification struct {
+	Request
+	Params DidOpenNotebookDocumentParams `json:"params"`
+}
+
+type DidOpenNotebookDocumentParams struct {
+	NotebookDocument  NotebookDocument   `json:"notebookDocument"`
+	CellTextDocuments []TextDocumentItem `json:"cellTextDocuments"`
+}
+
+type DidChangeNotebookDocumentNotification struct {
+	Request
+	Params DidChangeNotebookDocumentParams `json:"params"`
+}
+
+type DidChangeNotebookDocumentParams struct {
+	NotebookDocument NotebookDocumentIdentifier  `json:"notebookDocument"`
+	Change           NotebookDocumentChangeEvent `json:"change"`
+}
+
+type NotebookDocumentChangeEvent struct {
+	Cells *NotebookDocumentCellChanges `json:"cells,omitempty"`
+}
+
+type NotebookDocumentCellChanges struct {
+	Structure   *NotebookCellStructureChange `json:"structure,omitempty"`
+	Data        []NotebookCell               `json:"data,omitempty"`
+	TextContent []NotebookCellTextContent    `json:"textContent,omitempty"`
+}
+
+type NotebookCellStructureChange struct {
+	Array    NotebookCellArrayChange  `json:"array"`
+	DidOpen  []TextDocumentItem       `json:"didOpen,omitempty"`
+	DidClose []TextDocumentIdentifier `json:"didClose,omitempty"`
+}
+
+type NotebookCellArrayChange struct {
+	Start       int            `json:"start"`
+	DeleteCount int            `json:"deleteCount"`
+	Cells       []NotebookCell `json:"cells,omitempty"`
+}
+
+type NotebookCellTextContent struct {
+	Document VersionedTextDocumentIdentifier  `json:"document"`
+	Changes  []TextDocumentContentChangeEvent `json:"changes"`
+}
+
+type DidSaveNotebookDocumentNotification struct {
+	Request
+	Params DidSaveNotebookDocumentParams `json:"params"`
+}
+
+type DidSaveNotebookDocumentParams struct {
+	NotebookDocument NotebookDocumentIdentifier `json:"notebookDocument"`
+}
+
+type DidCloseNotebookDocumentNotification struct {
+	Request
+	Params DidCloseNotebookDocumentParams `json:"params"`
+}
+
+type DidCloseNotebookDocumentParams struct {
+	NotebookDocument  NotebookDocumentIdentifier `json:"notebookDocument"`
+	CellTextDocuments []TextDocumentIdentifier   `json:"cellTextDocuments"`
+}
+
+// notebook keeps the cells of an open notebook in order. The text of every
+// cell lives in Server.Files under the cell URI.
+type notebook struct {
+	cells []NotebookCell
+}
+
+func (s *Server) OpenNotebook(params DidOpenNotebookDocumentParams) {
+	s.notebooks[params.NotebookDocument.URI] = &notebook{cells: params.NotebookDocument.Cells}
+	for _, cell := range params.NotebookDocument.Cells {
+		s.cellKinds[cell.Document] = cell.Kind
+	}
+	for _, document := range params.CellTextDocuments {
+		s.Files[document.URI] = document.Text
+		s.languageIDs[document.URI] = document.LanguageID
+	}
+}
+
+// ChangeNotebook applies a notebook change and returns the URIs of the cells
+// that should be checked again because their text changed, and empty
+// diagnostics for each removed cell so that the client clears them.
+func (s *Server) ChangeNotebook(params DidChangeNotebookDocumentParams) ([]string, []*PublishDiagnosticsNotification) {
+	nb, ok := s.notebooks[params.NotebookDocument.URI]
+	cells := params.Change.Cells
+	if !ok || cells == nil {
+		return nil, nil
+	}
+
+	changed := []string{}
+	closed := []*PublishDiagnosticsNotification{}
+
+	if structure := cells.Structure; structure != nil {
+		array := structure.Array
+		start := min(array.Start, len(nb.cells))
+		end := min(start+array.DeleteCount, len(nb.cells))
+		nb.cells = append(nb.cells[:start], append(array.Cells, nb.cells[end:]...)...)
+
+		for _, cell := range array.Cells {
+			s.cellKinds[cell.Document] = cell.Kind
+		}
+		for _, document := range structure.DidOpen {
+			s.Files[document.URI] = document.Text
+			s.languageIDs[document.URI] = document.LanguageID
+			changed = append(changed, document.URI)
+		}
+		for _, document := range structure.DidClose {
+			s.CancelPending(document.URI)
+			delete(s.Files, document.URI)
+			delete(s.cellKinds, document.URI)
+			delete(s.languageIDs, document.URI)
+			closed = append(closed, NewDiagnostics(document.URI, []Diagnostic{}))
+		}
+	}
+
+	for _, cell := range cells.Data {
+		s.cellKinds[cell.Document] = cell.Kind
+	}
+
+	for _, content := range cells.TextContent {
+		if len(content.Changes) == 0 {
+			continue
+		}
+		s.Files[content.Document.URI] = content.Changes[len(content.Changes)-1].Text
+		changed = append(changed, content.Document.URI)
+	}
+
+	uris := []string{}
+	for _, uri := range changed {
+		if s.cellKinds[uri] != NotebookCellKindCode || s.ModelConfig.CheckComments {
+			uris = append(uris, uri)
+		}
+	}
+	return uris, closed
+}
+
+// CloseNotebook forgets the notebook and returns empty diagnostics for each
+// of its cells so that the client clears them.
+func (s *Server) CloseNotebook(params DidCloseNotebookDocumentParams) []*PublishDiagnosticsNotification {
+	notifications := []*PublishDiagnosticsNotification{}
+	for _, document := range params.CellTextDocuments {
+		delete(s.Files, document.URI)
+		delete(s.cellKinds, document.URI)
+		delete(s.languageIDs, document.URI)
+		notifications = append(notifications, NewDiagnostics(document.URI, []Diagnostic{}))
+	}
+	delete(s.notebooks, params.NotebookDocument.URI)
+
+	return notifications
+}
+
+// NotebookCells returns the URIs of the cells of a notebook that should be
+// checked. Code cells are only checked when comment checking is enabled.
+func (s *Server) NotebookCells(notebookURI string) []string {
+	nb, ok := s.notebooks[notebookURI]
+	if !ok {
+		return nil
+	}
+
+	uris := []string{}
+	for _, cell := range nb.cells {
+		if cell.Kind == NotebookCellKindCode && !s.ModelConfig.CheckComments {
+			continue
+		}
+		uris = append(uris, cell.Document)
+	}
+	return uris
+}
+
+// parseComments extracts the comments and docstrings of a code cell with the
+// parser of its language, or as Python when the language is unknown.
+// Consecutive comment lines are read as one paragraph.
+func (s *Server) parseComments(fileURI string, text string) []Sentence {
+	extract, ok := documentParsers[s.languageIDs[fileURI]]
+	if !ok {
+		extract = func(doc *document) []segment {
+			return commentSegments(doc, pythonSyntax)
+		}
+	}
+	return parseSegments(text, s.parseOptions(), extract)
+}
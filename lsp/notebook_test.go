@@ -0,0 +1,119 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func newTestServer() *Server {
+	return &Server{
+		Files:       make(map[string]string),
+		notebooks:   make(map[string]*notebook),
+		cellKinds:   make(map[string]int),
+		languageIDs: make(map[string]string),
+		pending:     make(map[string]*pendingCheck),
+	}
+}
+
+func TestNotebookCells(t *testing.T) {
+	server := newTestServer()
+	server.OpenNotebook(DidOpenNotebookDocumentParams{
+		NotebookDocument: NotebookDocument{
+			URI: "file:///test.ipynb",
+			Cells: []NotebookCell{
+				{Kind: NotebookCellKindMarkup, Document: "cell:1"},
+				{Kind: NotebookCellKindCode, Document: "cell:2"},
+			},
+		},
+		CellTextDocuments: []TextDocumentItem{
+			{URI: "cell:1", LanguageID: "markdown", Text: "Hello world."},
+			{URI: "cell:2", LanguageID: "python", Text: "# Load the data\nx = 1"},
+		},
+	})
+
+	server.ChangeNotebook(DidChangeNotebookDocumentParams{
+		NotebookDocument: NotebookDocumentIdentifier{URI: "file:///test.ipynb"},
+		Change: NotebookDocumentChangeEvent{Cells: &NotebookDocumentCellChanges{
+			Structure: &NotebookCellStructureChange{
+				Array:   NotebookCellArrayChange{Start: 1, Cells: []NotebookCell{{Kind: NotebookCellKindMarkup, Document: "cell:3"}}},
+				DidOpen: []TextDocumentItem{{URI: "cell:3", LanguageID: "markdown", Text: "More text."}},
+			},
+		}},
+	})
+
+	cells := server.NotebookCells("file:///test.ipynb")
+	if len(cells) != 2 || cells[0] != "cell:1" || cells[1] != "cell:3" {
+		t.Errorf("Expected markdown cells cell:1 and cell:3, got %v", cells)
+	}
+
+	server.ModelConfig.CheckComments = true
+	cells = server.NotebookCells("file:///test.ipynb")
+	if len(cells) != 3 || cells[2] != "cell:2" {
+		t.Errorf("Expected the code cell to be checked, got %v", cells)
+	}
+
+	sentences := server.parse("cell:2", server.Files["cell:2"])
+	expected := Sentence{Text: "Load the data", Range: Range{Position{0, 2}, Position{0, 15}}}
+	if len(sentences) != 1 {
+		t.Fatalf("Expected 1 sentence, got %v", sentences)
+	}
+	testSentence(t, sentences[0], expected)
+}
+
+func TestNotebookRemovedCells(t *testing.T) {
+	server := newTestServer()
+	server.OpenNotebook(DidOpenNotebookDocumentParams{
+		NotebookDocument: NotebookDocument{
+			URI: "file:///test.ipynb",
+			Cells: []NotebookCell{
+				{Kind: NotebookCellKindMarkup, Document: "cell:1"},
+				{Kind: NotebookCellKindMarkup, Document: "cell:2"},
+			},
+		},
+		CellTextDocuments: []TextDocumentItem{
+			{URI: "cell:1", LanguageID: "markdown", Text: "Hello world."},
+			{URI: "cell:2", LanguageID: "markdown", Text: "More text."},
+		},
+	})
+
+	_, closed := server.ChangeNotebook(DidChangeNotebookDocumentParams{
+		NotebookDocument: NotebookDocumentIdentifier{URI: "file:///test.ipynb"},
+		Change: NotebookDocumentChangeEvent{Cells: &NotebookDocumentCellChanges{
+			Structure: &NotebookCellStructureChange{
+				Array:    NotebookCellArrayChange{Start: 1, DeleteCount: 1},
+				DidClose: []TextDocumentIdentifier{{URI: "cell:2"}},
+			},
+		}},
+	})
+
+	if len(closed) != 1 || closed[0].Params.URI != "cell:2" || len(closed[0].Params.Diagnostics) != 0 {
+		t.Errorf("Expected empty diagnostics for cell:2, got %v", closed)
+	}
+	if _, ok := server.Files["cell:2"]; ok {
+		t.Errorf("Expected cell:2 to be forgotten")
+	}
+}
+
+func TestNotebookCodeCellLanguage(t *testing.T) {
+	server := newTestServer()
+	server.ModelConfig.Language = "de"
+	server.OpenNotebook(DidOpenNotebookDocumentParams{
+		NotebookDocument: NotebookDocument{
+			URI: "file:///test.ipynb",
+			Cells: []NotebookCell{
+				{Kind: NotebookCellKindCode, Document: "cell:1"},
+				{Kind: NotebookCellKindCode, Document: "cell:2"},
+			},
+		},
+		CellTextDocuments: []TextDocumentItem{
+			{URI: "cell:1", LanguageID: "javascript", Text: "// Lade die Daten\nlet x = 1"},
+			{URI: "cell:2", LanguageID: "unknown", Text: "# Lade die Daten\nx = 1"},
+		},
+	})
+
+	for _, uri := range []string{"cell:1", "cell:2"} {
+		sentences := server.parse(uri, server.Files[uri])
+		if len(sentences) != 1 || sentences[0].Text != "Lade die Daten" || sentences[0].language != "de" {
+			t.Errorf("Expected the comment of %s in de, got %v", uri, sentences)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package lsp
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OfficeLanguageID is the language id of the text of an office document, as
+// returned by ReadOfficeDocument joined by newlines: one paragraph per line.
+const OfficeLanguageID = "office"
+
+func init() {
+	registerParser(OfficeLanguageID, officeSegments)
+}
+
+// officeFormat tells where the paragraphs of an office document are.
+type officeFormat struct {
+	// part is the XML file of the archive holding the body
+	part string
+	// space is the namespace of the elements below
+	space      string
+	paragraphs []string
+	// text holds the character data of a paragraph, or any element when ""
+	text   string
+	tabs   []string
+	breaks []string
+	spaces string
+	// skipped are dropped with their content, in any namespace
+	skipped []xml.Name
+}
+
+var officeFormats = map[string]officeFormat{
+	".docx": {
+		part:       "word/document.xml",
+		space:      "http://schemas.openxmlformats.org/wordprocessingml/2006/main",
+		paragraphs: []string{"p"},
+		text:       "t",
+		tabs:       []string{"tab"},
+		breaks:     []string{"br", "cr"},
+	},
+	".odt": {
+		part:       "content.xml",
+		space:      "urn:oasis:names:tc:opendocument:xmlns:text:1.0",
+		paragraphs: []string{"p", "h"},
+		tabs:       []string{"tab"},
+		breaks:     []string{"line-break"},
+		spaces:     "s",
+		skipped: []xml.Name{
+			{Space: "urn:oasis:names:tc:opendocument:xmlns:text:1.0", Local: "note-citation"},
+			{Space: "urn:oasis:names:tc:opendocument:xmlns:text:1.0", Local: "tracked-changes"},
+			{Space: "urn:oasis:names:tc:opendocument:xmlns:office:1.0", Local: "annotation"},
+		},
+	},
+}
+
+// ReadOfficeDocument returns the paragraphs of a DOCX or ODT file, headings
+// included, without the empty ones. Line breaks inside a paragraph become
+// spaces.
+func ReadOfficeDocument(path string) ([]string, error) {
+	format, ok := officeFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, errors.New("not a .docx or .odt file")
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if file.Name != format.part {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return format.paragraphsOf(r)
+	}
+	return nil, errors.New(format.part + " is missing")
+}
+
+// paragraphsOf reads the paragraphs of the XML body of a document. Nested
+// paragraphs, like those of text boxes and notes, are paragraphs of their
+// own.
+func (f officeFormat) paragraphsOf(r io.Reader) ([]string, error) {
+	paragraphs := []string{}
+	open := []*strings.Builder{}
+	inText := f.text == ""
+	skipped := 0
+	// elements tells for each open element whether it's in f.space, as only
+	// their character data is text
+	elements := []bool{}
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			elements = append(elements, token.Name.Space == f.space)
+			if f.isSkipped(token.Name) {
+				skipped++
+				continue
+			}
+			if token.Name.Space != f.space {
+				continue
+			}
+			name := token.Name.Local
+			switch {
+			case contains(f.paragraphs, name):
+				open = append(open, &strings.Builder{})
+			case len(open) == 0 || skipped > 0:
+				// Outside of a paragraph, or in a skipped element
+			case name == f.text:
+				inText = true
+			case contains(f.tabs, name):
+				open[len(open)-1].WriteString("\t")
+			case contains(f.breaks, name):
+				open[len(open)-1].WriteString(" ")
+			case name == f.spaces:
+				count := 1
+				for _, attr := range token.Attr {
+					if attr.Name.Local == "c" {
+						count, _ = strconv.Atoi(attr.Value)
+					}
+				}
+				open[len(open)-1].WriteString(strings.Repeat(" ", max(count, 1)))
+			}
+		case xml.EndElement:
+			elements = elements[:len(elements)-1]
+			if f.isSkipped(token.Name) {
+				skipped--
+				continue
+			}
+			if token.Name.Space != f.space {
+				continue
+			}
+			name := token.Name.Local
+			switch {
+			case contains(f.paragraphs, name) && len(open) > 0:
+				text := strings.TrimSpace(open[len(open)-1].String())
+				open = open[:len(open)-1]
+				if text != "" {
+					paragraphs = append(paragraphs, text)
+				}
+			case name == f.text:
+				inText = false
+			}
+		case xml.CharData:
+			if inText && len(open) > 0 && skipped == 0 && elements[len(elements)-1] {
+				open[len(open)-1].WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(string(token)))
+			}
+		}
+	}
+	return paragraphs, nil
+}
+
+func (f officeFormat) isSkipped(name xml.Name) bool {
+	for _, skipped := range f.skipped {
+		if name == skipped {
+			return true
+		}
+	}
+	return false
+}
+
+// officeSegments extracts the paragraphs of an office document, one per
+// line.
+func officeSegments(doc *document) []segment {
+	segments := []segment{}
+	for i := range doc.lineStarts {
+		line := doc.lineSpan(i)
+		s := segment{}
+		s.add(doc, line.start, line.end)
+		if hasProse(s.text) {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
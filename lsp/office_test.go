@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeArchive(t *testing.T, path, name, content string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	w, err := archive.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(content))
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadOfficeDocument(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		Name     string
+		Part     string
+		Content  string
+		Expected []string
+	}{
+		{
+			Name: "spec.docx",
+			Part: "word/document.xml",
+			Content: `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Product spec</w:t></w:r></w:p>
+<w:p></w:p>
+<w:p><w:r><w:t xml:space="preserve">The app </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>support</w:t></w:r><w:r><w:t xml:space="preserve"> dark mode.</w:t><w:br/><w:t>It ship soon.</w:t></w:r></w:p>
+<w:p><w:r><w:delText>Removed text.</w:delText><w:t>Price:</w:t><w:tab/><w:t>free &amp; open.</w:t></w:r></w:p>
+</w:body></w:document>`,
+			Expected: []string{"Product spec", "The app support dark mode. It ship soon.", "Price:\tfree & open."},
+		},
+		{
+			Name: "spec.odt",
+			Part: "content.xml",
+			Content: `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><office:body><office:text>
+<text:tracked-changes><text:changed-region><text:deletion><text:p>Deleted.</text:p></text:deletion></text:changed-region></text:tracked-changes>
+<text:h text:outline-level="1">Product spec</text:h>
+<text:p>The app <text:span>support</text:span> dark<text:s text:c="2"/>mode.<text:note><text:note-citation>1</text:note-citation><text:note-body><text:p>A note.</text:p></text:note-body></text:note></text:p>
+<text:list><text:list-item><text:p>First<text:line-break/>item.</text:p></text:list-item></text:list>
+<text:p>Hello<office:annotation><dc:creator>Jane Doe</dc:creator><dc:date>2024-01-01T10:00:00</dc:date><text:p>A comment.</text:p></office:annotation> world.</text:p>
+</office:text></office:body></office:document-content>`,
+			Expected: []string{"Product spec", "A note.", "The app support dark  mode.", "First item.", "Hello world."},
+		},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.Name)
+		writeArchive(t, path, test.Part, test.Content)
+		paragraphs, err := ReadOfficeDocument(path)
+		if err != nil {
+			t.Errorf("%s: %s", test.Name, err)
+			continue
+		}
+		if len(paragraphs) != len(test.Expected) {
+			t.Errorf("%s: Expected %d paragraphs, got %q", test.Name, len(test.Expected), paragraphs)
+			continue
+		}
+		for i := range test.Expected {
+			if paragraphs[i] != test.Expected[i] {
+				t.Errorf("Expected %q, got %q", test.Expected[i], paragraphs[i])
+			}
+		}
+	}
+
+	if _, err := ReadOfficeDocument(filepath.Join(dir, "notes.txt")); err == nil {
+		t.Errorf("Expected an error for a .txt file")
+	}
+}
+
+func TestOfficeSegments(t *testing.T) {
+	text := "Product spec\nThe app support dark mode. It ship soon.\n\t42"
+	expected := []Sentence{
+		{Text: "Product spec", Range: Range{Position{0, 0}, Position{0, 12}}},
+		{Text: "The app support dark mode.", Range: Range{Position{1, 0}, Position{1, 26}}},
+		{Text: "It ship soon.", Range: Range{Position{1, 27}, Position{1, 40}}},
+	}
+
+	result := parseSegments(text, parseOptions{language: defaultLanguage}, officeSegments)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i := range expected {
+		testSentence(t, result[i], expected[i])
+	}
+}
@@ -0,0 +1,354 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	registerParser("org", orgSegments)
+}
+
+var (
+	orgHeadlineRegex = regexp.MustCompile(`^(\*+)[ \t]+`)
+	orgPriorityRegex = regexp.MustCompile(`^\[#[A-Za-z0-9]\][ \t]*`)
+	orgTagsRegex     = regexp.MustCompile(`[ \t]+:(?:[\w@#%]+:)+$`)
+	orgCookieRegex   = regexp.MustCompile(`[ \t]*\[(?:\d*/\d*|\d*%)\]`)
+	orgBlockRegex    = regexp.MustCompile(`(?i)^[ \t]*#\+begin_(\S+)`)
+	orgKeywordRegex  = regexp.MustCompile(`^[ \t]*#\+[^\s:]+:`)
+	orgTodoRegex     = regexp.MustCompile(`(?i)^[ \t]*#\+(?:todo|seq_todo|typ_todo):(.*)$`)
+	orgDrawerRegex   = regexp.MustCompile(`^[ \t]*:[\w-]+:[ \t]*$`)
+	orgPlanningRegex = regexp.MustCompile(`^[ \t]*(?:SCHEDULED|DEADLINE|CLOSED):`)
+	orgListRegex     = regexp.MustCompile(`^[ \t]*(?:[-+]|[ \t]+\*|\d+[.)]|[A-Za-z][.)])[ \t]+(?:\[[ X-]\][ \t]+)?`)
+	orgTermRegex     = regexp.MustCompile(`^(.*?)[ \t]+::(?:[ \t]+|$)`)
+	orgFootnoteRegex = regexp.MustCompile(`^\[fn:[^\]\s]+\][ \t]*`)
+	orgRuleRegex     = regexp.MustCompile(`^[ \t]*-{5,}$`)
+
+	orgLinkRegex      = regexp.MustCompile(`^\[\[([^\]]+)\](?:\[([^\]]+)\])?\]`)
+	orgURLRegex       = regexp.MustCompile(`^(?:https?|ftp|mailto|file):[^\s\[\]<>]*[^\s\[\]<>.,;:!?)'"]`)
+	orgTimestampRegex = regexp.MustCompile(`^[<\[]\d{4}-\d{2}-\d{2}[^\]>\n]*[>\]](?:--[<\[]\d{4}-\d{2}-\d{2}[^\]>\n]*[>\]])?`)
+	orgMacroRegex     = regexp.MustCompile(`^\{\{\{[^}]*\}\}\}`)
+	orgFootnoteRef    = regexp.MustCompile(`^\[fn:[^\]]*\]`)
+	orgInlineSrcRegex = regexp.MustCompile(`^src_\w+(?:\[[^\]]*\])?\{[^}]*\}`)
+)
+
+// orgProseBlocks are the blocks whose content is checked. All other blocks,
+// like src, example and export, are skipped.
+var orgProseBlocks = map[string]bool{
+	"quote": true, "verse": true, "center": true,
+}
+
+// orgSegments extracts the prose of an Org document: headlines without their
+// TODO keyword, priority and tags, paragraphs, list items, footnotes and table
+// cells. Blocks other than quotes, drawers, keyword lines, comments and
+// fixed-width lines are skipped. Commented subtrees are skipped as well.
+func orgSegments(doc *document) []segment {
+	p := &orgParser{doc: doc, keywords: map[string]bool{"TODO": true, "DONE": true}}
+	for i := range doc.lineStarts {
+		line := doc.lineSpan(i)
+		line.end = line.start + len(strings.TrimRight(doc.text[line.start:line.end], " \t"))
+		p.lines = append(p.lines, line)
+
+		// #+TODO: lines add keywords, with "|" between the open and done ones
+		if match := orgTodoRegex.FindStringSubmatch(doc.text[line.start:line.end]); match != nil {
+			for _, keyword := range strings.Fields(match[1]) {
+				keyword, _, _ = strings.Cut(keyword, "(")
+				if keyword != "|" {
+					p.keywords[keyword] = true
+				}
+			}
+		}
+	}
+	p.parse()
+	return p.segments
+}
+
+type orgParser struct {
+	doc      *document
+	lines    []span
+	keywords map[string]bool
+	segments []segment
+}
+
+func (p *orgParser) text(i int) string {
+	return p.doc.text[p.lines[i].start:p.lines[i].end]
+}
+
+// startsElement reports whether a line ends the paragraph before it.
+func (p *orgParser) startsElement(text string) bool {
+	trimmed := strings.TrimLeft(text, " \t")
+	return text == "" || orgHeadlineRegex.MatchString(text) || orgListRegex.MatchString(text) ||
+		strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "|") || trimmed == ":" ||
+		strings.HasPrefix(trimmed, ": ") || orgDrawerRegex.MatchString(text) || orgRuleRegex.MatchString(text) ||
+		orgFootnoteRegex.MatchString(text)
+}
+
+func (p *orgParser) parse() {
+	for i := 0; i < len(p.lines); {
+		text := p.text(i)
+		trimmed := strings.TrimLeft(text, " \t")
+		switch {
+		case text == "" || orgRuleRegex.MatchString(text) || orgPlanningRegex.MatchString(text):
+			i++
+
+		case orgHeadlineRegex.MatchString(text):
+			i = p.headline(i)
+
+		case orgBlockRegex.MatchString(text):
+			name := strings.ToLower(orgBlockRegex.FindStringSubmatch(text)[1])
+			closing := "#+end_" + name
+			end := i + 1
+			for end < len(p.lines) && !strings.HasPrefix(strings.ToLower(strings.TrimLeft(p.text(end), " \t")), closing) {
+				end++
+			}
+			if orgProseBlocks[name] {
+				p.paragraphs(i+1, end)
+			}
+			i = end + 1
+
+		case orgDrawerRegex.MatchString(text):
+			end := i + 1
+			for end < len(p.lines) && !strings.EqualFold(strings.TrimSpace(p.text(end)), ":END:") {
+				end++
+			}
+			i = end + 1
+
+		// Keywords, comments and fixed-width lines
+		case orgKeywordRegex.MatchString(text) || trimmed == "#" || strings.HasPrefix(trimmed, "# ") ||
+			trimmed == ":" || strings.HasPrefix(trimmed, ": "):
+			i++
+
+		case strings.HasPrefix(trimmed, "|"):
+			p.table(i)
+			i++
+
+		default:
+			i = p.paragraph(i)
+		}
+	}
+}
+
+// paragraphs parses the lines of a block as paragraphs and list items.
+func (p *orgParser) paragraphs(start, end int) {
+	for i := start; i < end; {
+		if p.text(i) == "" {
+			i++
+			continue
+		}
+		i = min(p.paragraph(i), end)
+	}
+}
+
+// headline adds the title of the headline at line i, and returns the line
+// after it. A commented headline is skipped with its subtree.
+func (p *orgParser) headline(i int) int {
+	line := p.lines[i]
+	text := p.text(i)
+	stars := orgHeadlineRegex.FindStringSubmatch(text)[1]
+	start := line.start + len(orgHeadlineRegex.FindString(text))
+
+	if keyword, _, _ := strings.Cut(p.doc.text[start:line.end], " "); keyword == "COMMENT" {
+		for i++; i < len(p.lines); i++ {
+			if match := orgHeadlineRegex.FindStringSubmatch(p.text(i)); match != nil && len(match[1]) <= len(stars) {
+				break
+			}
+		}
+		return i
+	}
+	if keyword, _, _ := strings.Cut(p.doc.text[start:line.end], " "); p.keywords[keyword] {
+		start += len(keyword)
+		for start < line.end && p.doc.text[start] == ' ' {
+			start++
+		}
+	}
+	start += len(orgPriorityRegex.FindString(p.doc.text[start:line.end]))
+
+	end := line.end
+	if tags := orgTagsRegex.FindStringIndex(p.doc.text[start:end]); tags != nil {
+		end = start + tags[0]
+	}
+
+	// Statistics cookies like [2/3] are dropped
+	s := segment{}
+	from := start
+	for _, cookie := range orgCookieRegex.FindAllStringIndex(p.doc.text[start:end], -1) {
+		s.add(p.doc, from, start+cookie[0])
+		from = start + cookie[1]
+	}
+	s.add(p.doc, from, end)
+	p.add(s)
+	return i + 1
+}
+
+// paragraph adds the paragraph or list item starting at line i, and returns
+// the line after it.
+func (p *orgParser) paragraph(i int) int {
+	first := p.lines[i]
+	text := p.text(i)
+	isItem := orgListRegex.MatchString(text)
+	switch {
+	case isItem:
+		first.start += len(orgListRegex.FindString(text))
+		if match := orgTermRegex.FindStringSubmatchIndex(p.doc.text[first.start:first.end]); match != nil {
+			term := segment{}
+			term.add(p.doc, first.start+match[2], first.start+match[3])
+			p.add(term)
+			first.start += match[1]
+		}
+	case orgFootnoteRegex.MatchString(text):
+		first.start += len(orgFootnoteRegex.FindString(text))
+	default:
+		first.start += len(text) - len(strings.TrimLeft(text, " \t"))
+	}
+
+	s := segment{}
+	s.add(p.doc, first.start, first.end)
+	i++
+	for ; i < len(p.lines) && !p.startsElement(p.text(i)); i++ {
+		line := p.lines[i]
+		line.start += len(p.text(i)) - len(strings.TrimLeft(p.text(i), " \t"))
+		s.join(" ", line.start-1)
+		s.add(p.doc, line.start, line.end)
+	}
+	p.add(s)
+	return i
+}
+
+// table adds the cells of a table row. Rules like |---+---| are skipped.
+func (p *orgParser) table(i int) {
+	line := p.lines[i]
+	text := p.text(i)
+	start := line.start + strings.IndexByte(text, '|') + 1
+	if start < line.end && p.doc.text[start] == '-' {
+		return
+	}
+	for start < line.end {
+		end := strings.IndexByte(p.doc.text[start:line.end], '|')
+		if end == -1 {
+			end = line.end
+		} else {
+			end += start
+		}
+		cell := p.doc.text[start:end]
+		s := segment{}
+		from := start + len(cell) - len(strings.TrimLeft(cell, " \t"))
+		s.add(p.doc, from, from+len(strings.TrimSpace(cell)))
+		p.add(s)
+		start = end + 1
+	}
+}
+
+func (p *orgParser) add(s segment) {
+	// A trailing \\ is a line break
+	if strings.HasSuffix(s.text, `\\`) {
+		s = s.slice(0, len(s.text)-2)
+	}
+	if text := orgInline(s); hasProse(text.text) {
+		p.segments = append(p.segments, text)
+	}
+}
+
+// orgInline removes Org inline markup: bold, italic, underline and strike
+// through markers, link targets and footnote references. Verbatim, code,
+// URLs, timestamps, macros and inline source blocks become a placeholder.
+func orgInline(s segment) segment {
+	result := segment{}
+	text := s.text
+
+	before := func(i int) rune {
+		if i == 0 {
+			return ' '
+		}
+		r, _ := utf8.DecodeLastRuneInString(text[:i])
+		return r
+	}
+	after := func(i int) rune {
+		if i >= len(text) {
+			return ' '
+		}
+		r, _ := utf8.DecodeRuneInString(text[i:])
+		return r
+	}
+	// emphasis returns the offset of the marker closing the markup at i, or
+	// -1. Markers need whitespace or punctuation outside and text inside.
+	emphasis := func(i int) int {
+		if pre := before(i); !unicode.IsSpace(pre) && !strings.ContainsRune(`-({'"`, pre) || unicode.IsSpace(after(i+1)) {
+			return -1
+		}
+		for j := i + 2; j < len(text); j++ {
+			post := after(j + 1)
+			if text[j] == text[i] && !unicode.IsSpace(before(j)) && (unicode.IsSpace(post) || strings.ContainsRune(`-.,;:!?')}["`, post)) {
+				return j
+			}
+		}
+		return -1
+	}
+	placeholder := func(start, end int, text string) {
+		result.replace(text, s.offsets[start], s.offsets[end-1]+1)
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '=' || c == '~':
+			if end := emphasis(i); end != -1 {
+				placeholder(i, end+1, codePlaceholder)
+				i = end + 1
+				continue
+			}
+
+		case c == '*' || c == '/' || c == '_' || c == '+':
+			if end := emphasis(i); end != -1 {
+				result.append(orgInline(s.slice(i+1, end)))
+				i = end + 1
+				continue
+			}
+
+		case c == '[' && orgLinkRegex.MatchString(text[i:]):
+			match := orgLinkRegex.FindStringSubmatchIndex(text[i:])
+			if match[4] != -1 {
+				result.append(orgInline(s.slice(i+match[4], i+match[5])))
+			} else {
+				placeholder(i, i+match[1], urlPlaceholder)
+			}
+			i += match[1]
+			continue
+
+		case c == '[' && orgFootnoteRef.MatchString(text[i:]):
+			i += len(orgFootnoteRef.FindString(text[i:]))
+			continue
+
+		case (c == '<' || c == '[') && orgTimestampRegex.MatchString(text[i:]):
+			end := i + len(orgTimestampRegex.FindString(text[i:]))
+			placeholder(i, end, codePlaceholder)
+			i = end
+			continue
+
+		case c == '{' && orgMacroRegex.MatchString(text[i:]):
+			end := i + len(orgMacroRegex.FindString(text[i:]))
+			placeholder(i, end, codePlaceholder)
+			i = end
+			continue
+
+		case c == 's' && unicode.IsSpace(before(i)) && orgInlineSrcRegex.MatchString(text[i:]):
+			end := i + len(orgInlineSrcRegex.FindString(text[i:]))
+			placeholder(i, end, codePlaceholder)
+			i = end
+			continue
+
+		case unicode.IsSpace(before(i)) && orgURLRegex.MatchString(text[i:]):
+			end := i + len(orgURLRegex.FindString(text[i:]))
+			placeholder(i, end, urlPlaceholder)
+			i = end
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		result.append(s.slice(i, i+size))
+		i += size
+	}
+
+	return result
+}
@@ -0,0 +1,69 @@
+package lsp
+
+import "testing"
+
+func TestOrgSegments(t *testing.T) {
+	text := `#+TITLE: Project notes
+#+TODO: TODO NEXT | DONE
+* NEXT [#A] Write the *first* draft [1/2] :work:urgent:
+  SCHEDULED: <2024-05-01 Wed>
+  :PROPERTIES:
+  :ID: 1234
+  :END:
+This paragraph has =verbatim= and ~code~ with a [[https://orgmode.org][link]].
+It continues /here/ on a second line.
+
+- [X] First item is done.
+- Term :: Its description.
+  1. Nested item here.
+
+#+BEGIN_SRC go
+fmt.Println("skip")
+#+END_SRC
+
+#+begin_quote
+Quoted text is prose.
+#+end_quote
+
+# A comment line
+: fixed width
+
+| Name | Description   |
+|------+---------------|
+| foo  | Does a thing. |
+
+* COMMENT Skipped subtree
+Hidden text.
+** Hidden child
+* Last headline
+See [[file:notes.org]] and https://example.com now.[fn:1]
+
+[fn:1] The footnote text.
+`
+
+	expected := []Sentence{
+		{Text: "Write the first draft", Range: Range{Position{2, 12}, Position{2, 35}}},
+		{Text: "This paragraph has ⟦code⟧ and ⟦code⟧ with a link.", Range: Range{Position{7, 0}, Position{7, 78}}},
+		{Text: "It continues here on a second line.", Range: Range{Position{8, 0}, Position{8, 37}}},
+		{Text: "First item is done.", Range: Range{Position{10, 6}, Position{10, 25}}},
+		{Text: "Term", Range: Range{Position{11, 2}, Position{11, 6}}},
+		{Text: "Its description.", Range: Range{Position{11, 10}, Position{11, 26}}},
+		{Text: "Nested item here.", Range: Range{Position{12, 5}, Position{12, 22}}},
+		{Text: "Quoted text is prose.", Range: Range{Position{19, 0}, Position{19, 21}}},
+		{Text: "Name", Range: Range{Position{25, 2}, Position{25, 6}}},
+		{Text: "Description", Range: Range{Position{25, 9}, Position{25, 20}}},
+		{Text: "foo", Range: Range{Position{27, 2}, Position{27, 5}}},
+		{Text: "Does a thing.", Range: Range{Position{27, 9}, Position{27, 22}}},
+		{Text: "Last headline", Range: Range{Position{32, 2}, Position{32, 15}}},
+		{Text: "See ⟦url⟧ and ⟦url⟧ now.", Range: Range{Position{33, 0}, Position{33, 51}}},
+		{Text: "The footnote text.", Range: Range{Position{35, 7}, Position{35, 25}}},
+	}
+
+	result := parseSegments(text, parseOptions{language: defaultLanguage}, orgSegments)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i := range expected {
+		testSentence(t, result[i], expected[i])
+	}
+}
@@ -1,8 +1,8 @@
 package lsp
 
 import (
-	"regexp"
 	"strings"
+	"unicode"
 )
 
 type Position struct {
@@ -15,92 +15,216 @@ type Range struct {
 	End   Position `json:"end"`
 }
 
+// Sentence is a sentence of a document. Text is the normalized form that is
+// checked, with markup stripped and whitespace collapsed, while Source is the
+// exact slice of the document that Range covers.
 type Sentence struct {
-	Text  string `json:"text"`
-	Range Range  `json:"range"`
+	Text   string `json:"text"`
+	Source string `json:"source"`
+	Range  Range  `json:"range"`
+
+	// doc and offsets map every byte of Text back to the document
+	doc     *document
+	offsets []int
+
+	// disabled lists the categories turned off by directives
+	disabled []string
+	// language is set when the sentence isn't in the configured language
+	language string
 }
 
-// Define a regular expression to match sentence-ending punctuation.
-var paragraphRegex = regexp.MustCompile(`[.?!](\s+|$)`)
-
+// parse extracts the sentences of a markdown document. Only prose blocks,
+// paragraphs and headings, wherever they are nested, produce sentences, and
+// their inline markup is stripped. HTML blocks only contribute the text of
+// prose elements like <p> and <summary> and the alt text of images, and
+// every table cell is a segment of its own.
 func parse(text string) []Sentence {
-	lines := strings.Split(text, "\n")
+	return parseWithLanguage(text, defaultLanguage)
+}
+
+// parseWithLanguage parses a markdown document with all exclusions and splits
+// its sentences with the segmenter of language.
+func parseWithLanguage(text string, language string) []Sentence {
+	return parseDocument(text, parseOptions{language: language, exclusions: exclusions})
+}
+
+// documentParsers extract the prose of documents that aren't markdown, by
+// their language id.
+var documentParsers = map[string]func(doc *document) []segment{}
+
+func registerParser(languageID string, extract func(doc *document) []segment) {
+	documentParsers[languageID] = extract
+}
 
-	remaining := Sentence{}
+// parseSegments splits the prose extracted from a document into sentences.
+func parseSegments(text string, options parseOptions, extract func(doc *document) []segment) []Sentence {
+	doc := newDocument(text)
+	doc.setLanguage(options.language)
+	doc.messageFuncs = options.messageFuncs
 	result := []Sentence{}
 
-	frontMatterChecked := false
-	skip := false
+	for _, s := range extract(doc) {
+		result = append(result, doc.sentences(s)...)
+	}
+
+	return result
+}
+
+// parseOptions are the settings of a server that affect parsing.
+type parseOptions struct {
+	language     string
+	exclusions   []Exclusion
+	messageFuncs []string
+}
 
-	for lineNumber, line := range lines {
-		// Ignore front matter
-		if line == "+++" || line == "---" {
-			if lineNumber == 0 {
-				frontMatterChecked = !frontMatterChecked
+// parseDocument parses a markdown document. The front matter can set another
+// language than the one in options.
+func parseDocument(text string, options parseOptions) []Sentence {
+	doc := newDocument(text)
+	result := []Sentence{}
+
+	fm := parseFrontMatter(doc)
+	if fm.disabled {
+		return result
+	}
+	language := options.language
+	if fm.language != "" {
+		language = fm.language
+	}
+	doc.setLanguage(language)
+	doc.exclusions = options.exclusions
+
+	root := parseMarkdown(doc.text, fm.end, options.exclusions)
+	for _, s := range append(fm.prose, markdownSegments(doc, root)...) {
+		result = append(result, doc.sentences(s)...)
+	}
+
+	directives := markdownDirectives(doc, root)
+	if len(fm.ignore) > 0 {
+		directives = append([]directive{{kind: directiveDisable, line: -1, categories: fm.ignore}}, directives...)
+	}
+	return applyDirectives(result, directives)
+}
+
+func markdownSegments(doc *document, root *block) []segment {
+	segments := []segment{}
+
+	walkBlocks(root, func(b *block) {
+		if b.kind == blockHTML {
+			// Only blocks of tags can hold prose, not comments or <pre>
+			if b.htmlType != 6 && b.htmlType != 7 {
+				return
+			}
+			s := segment{}
+			for i, line := range b.lines {
+				if i > 0 {
+					s.join("\n", line.start-1)
+				}
+				s.add(doc, line.start, line.end)
 			}
-			skip = !skip
-			continue
+			segments = append(segments, htmlSegments(s)...)
+			return
 		}
 
-		// Ignore code blocks
-		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
-			skip = !skip
-			continue
+		for _, s := range proseSegments(doc, b) {
+			if text := stripInline(s); hasProse(text.text) {
+				segments = append(segments, text)
+			}
+			segments = append(segments, inlineSegments(s)...)
 		}
+	})
 
-		// Ignore links and HTML comments
-		if strings.Contains(line, "]: http") || strings.HasPrefix(line, "<!-") || skip {
-			continue
-		}
+	return segments
+}
 
-		if strings.Trim(line, " ") == "" {
-			if len(remaining.Text) > 0 {
-				result = append(result, remaining)
-				remaining = Sentence{}
-			}
-			continue
+// proseSegments returns the text of a paragraph or heading, or the cells of a
+// table, with inline exclusions replaced but the markup still in place.
+func proseSegments(doc *document, b *block) []segment {
+	segments := []segment{}
+
+	switch b.kind {
+	case blockParagraph, blockHeading:
+		s := segment{}
+		s.addLines(doc, b.lines)
+		if s.text != "" {
+			segments = append(segments, excludeInline(s, doc.exclusions))
 		}
 
-		character := 0
-		parts := paragraphRegex.Split(line, -1)
-		for i, part := range parts {
-			start := Position{Line: lineNumber, Character: character}
-			end := Position{Line: lineNumber, Character: character + len(part)}
-			if remaining.Text != "" {
-				remaining.Range.End.Character = len(part)
-				remaining.Range.End.Line = lineNumber
-				remaining.Text += " " + part
-
-				result = append(result, remaining)
-				remaining = Sentence{}
-				character += len(part) + 2
+	case blockTable:
+		for i, row := range b.lines {
+			// The second row separates the header from the body
+			if i == 1 {
 				continue
 			}
-
-			if i == 0 && strings.HasPrefix(part, "- ") {
-				result = append(result, Sentence{
-					Text:  part,
-					Range: Range{Start: start, End: end},
-				})
-				character += len(part) + 2
-				continue
+			for _, cell := range tableCells(doc.text, row) {
+				s := segment{}
+				s.add(doc, cell.start, cell.end)
+				if s.text != "" {
+					segments = append(segments, excludeInline(s, doc.exclusions))
+				}
 			}
+		}
+	}
 
-			if i+1 == len(parts) {
-				remaining = Sentence{Text: part, Range: Range{Start: start, End: end}}
-			} else {
-				result = append(result, Sentence{
-					Text:  part,
-					Range: Range{Start: start, End: end},
-				})
-				character += len(part) + 2
-			}
+	return segments
+}
+
+// hasProse reports whether text has any letters outside of placeholders, so
+// that numbers and code, like in table cells, are skipped.
+func hasProse(text string) bool {
+	text = strings.ReplaceAll(text, codePlaceholder, "")
+	text = strings.ReplaceAll(text, urlPlaceholder, "")
+	return strings.IndexFunc(text, unicode.IsLetter) != -1
+}
+
+// frontMatterEnd returns the offset just after the front matter at the top of
+// the document, or 0 when there is none.
+func frontMatterEnd(text string) int {
+	first, _, _ := strings.Cut(text, "\n")
+	marker := strings.TrimRight(first, "\r")
+	if marker != "---" && marker != "+++" {
+		return 0
+	}
+
+	offset := len(first) + 1
+	for offset < len(text) {
+		line, _, _ := strings.Cut(text[offset:], "\n")
+		offset += len(line) + 1
+		if strings.TrimRight(line, "\r") == marker {
+			return min(offset, len(text))
 		}
 	}
 
-	if remaining.Text != "" {
-		result = append(result, remaining)
+	return 0
+}
+
+// locate maps a byte offset in sentence.Text back to a position in the
+// document.
+func (s Sentence) locate(offset int) Position {
+	if s.doc == nil {
+		return Position{Line: s.Range.Start.Line, Character: s.Range.Start.Character + offset}
+	}
+	if offset < len(s.offsets) {
+		return s.doc.position(s.offsets[offset])
+	}
+	return s.Range.End
+}
+
+// source returns the document text behind sentence.Text[start:end].
+func (s Sentence) source(start, end int) string {
+	if s.doc == nil {
+		return s.Text[start:end]
+	}
+	if start == end {
+		return ""
 	}
+	return s.doc.text[s.offsets[start] : s.offsets[end-1]+1]
+}
 
-	return result
+// rangeOf returns the document range of sentence.Text[start:end].
+func (s Sentence) rangeOf(start, end int) Range {
+	if start == end || s.doc == nil {
+		return Range{Start: s.locate(start), End: s.locate(end)}
+	}
+	return Range{Start: s.locate(start), End: s.doc.position(s.offsets[end-1] + 1)}
 }
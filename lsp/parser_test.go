@@ -14,41 +14,66 @@ func TestParse(t *testing.T) {
 		ParserTest{
 			Text: `Hello, world! This is a test.`,
 			Expected: []Sentence{
-				Sentence{"Hello, world", Range{Position{0, 0}, Position{0, 12}}},
-				Sentence{"This is a test", Range{Position{0, 14}, Position{0, 28}}},
+				Sentence{Text: "Hello, world!", Range: Range{Position{0, 0}, Position{0, 13}}},
+				Sentence{Text: "This is a test.", Range: Range{Position{0, 14}, Position{0, 29}}},
 			},
 		},
 		ParserTest{
 			Text: "Hello, world! This is a\ntest. This is another test.",
 			Expected: []Sentence{
-				Sentence{"Hello, world", Range{Position{0, 0}, Position{0, 12}}},
-				Sentence{"This is a test", Range{Position{0, 14}, Position{1, 4}}},
-				Sentence{"This is another test", Range{Position{1, 6}, Position{1, 26}}},
+				Sentence{Text: "Hello, world!", Range: Range{Position{0, 0}, Position{0, 13}}},
+				Sentence{Text: "This is a test.", Range: Range{Position{0, 14}, Position{1, 5}}},
+				Sentence{Text: "This is another test.", Range: Range{Position{1, 6}, Position{1, 27}}},
 			},
 		},
 		ParserTest{
 			Text: "- Hello world. This is\n  a sentence\n- This is a test",
 			Expected: []Sentence{
-				Sentence{"- Hello world", Range{Position{0, 0}, Position{0, 13}}},
-				Sentence{"This is   a sentence", Range{Position{0, 15}, Position{1, 12}}},
-				Sentence{"- This is a test", Range{Position{2, 0}, Position{2, 16}}},
+				Sentence{Text: "Hello world.", Range: Range{Position{0, 2}, Position{0, 14}}},
+				Sentence{Text: "This is a sentence", Range: Range{Position{0, 15}, Position{1, 12}}},
+				Sentence{Text: "This is a test", Range: Range{Position{2, 2}, Position{2, 16}}},
 			},
 		},
 		ParserTest{
 			Text: "Hello world\n  \nThis is a sentence\n\n- This is a test",
 			Expected: []Sentence{
-				Sentence{"Hello world", Range{Position{0, 0}, Position{0, 11}}},
-				Sentence{"This is a sentence", Range{Position{2, 0}, Position{2, 18}}},
-				Sentence{"- This is a test", Range{Position{4, 0}, Position{4, 16}}},
+				Sentence{Text: "Hello world", Range: Range{Position{0, 0}, Position{0, 11}}},
+				Sentence{Text: "This is a sentence", Range: Range{Position{2, 0}, Position{2, 18}}},
+				Sentence{Text: "This is a test", Range: Range{Position{4, 2}, Position{4, 16}}},
 			},
 		},
 		ParserTest{
 			Text: `I am trying to write a simple Redis client in Rust. As part of that exercise, I have read the source code of
 Node.js Redis Client. I want to jot down some notes on how it works.`,
 			Expected: []Sentence{
-				Sentence{"I am trying to write a simple Redis client in Rust", Range{Position{0, 0}, Position{0, 50}}},
-				Sentence{"As part of that exercise, I have read the source code of Node.js Redis Client", Range{Position{0, 52}, Position{1, 20}}},
-				Sentence{"I want to jot down some notes on how it works", Range{Position{1, 22}, Position{1, 67}}},
+				Sentence{Text: "I am trying to write a simple Redis client in Rust.", Range: Range{Position{0, 0}, Position{0, 51}}},
+				Sentence{Text: "As part of that exercise, I have read the source code of Node.js Redis Client.", Range: Range{Position{0, 52}, Position{1, 21}}},
+				Sentence{Text: "I want to jot down some notes on how it works.", Range: Range{Position{1, 22}, Position{1, 68}}},
+			},
+		},
+		ParserTest{
+			Text: "# Heading one #\n\nSetext heading\n---\n\n1. First item\n2) Second item\n\n> Quoted text\n> continues here.",
+			Expected: []Sentence{
+				Sentence{Text: "Heading one", Range: Range{Position{0, 2}, Position{0, 13}}},
+				Sentence{Text: "Setext heading", Range: Range{Position{2, 0}, Position{2, 14}}},
+				Sentence{Text: "First item", Range: Range{Position{5, 3}, Position{5, 13}}},
+				Sentence{Text: "Second item", Range: Range{Position{6, 3}, Position{6, 14}}},
+				Sentence{Text: "Quoted text continues here.", Range: Range{Position{8, 2}, Position{9, 17}}},
+			},
+		},
+		ParserTest{
+			Text: "Some text\n\n    indented code\n\n````\n```\nnested fence\n```\n````\n\n---\n\nAfter the break",
+			Expected: []Sentence{
+				Sentence{Text: "Some text", Range: Range{Position{0, 0}, Position{0, 9}}},
+				Sentence{Text: "After the break", Range: Range{Position{12, 0}, Position{12, 15}}},
+			},
+		},
+		ParserTest{
+			Text: "---\ntitle: Hello\n---\n\n- > Quote in a list\n  * Nested item\n\n[link]: https://example.com\n<!-- comment -->\n",
+			Expected: []Sentence{
+				Sentence{Text: "Hello", Range: Range{Position{1, 7}, Position{1, 12}}},
+				Sentence{Text: "Quote in a list", Range: Range{Position{4, 4}, Position{4, 19}}},
+				Sentence{Text: "Nested item", Range: Range{Position{5, 4}, Position{5, 15}}},
 			},
 		},
 	}
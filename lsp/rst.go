@@ -0,0 +1,439 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	registerParser("restructuredtext", rstSegments)
+	registerParser("rst", rstSegments)
+}
+
+// rstLine is a line of a reStructuredText document. start is the offset of
+// its text after the indentation, and blank lines have start == end.
+type rstLine struct {
+	start  int
+	end    int
+	indent int
+}
+
+func (l rstLine) blank() bool {
+	return l.start == l.end
+}
+
+var (
+	rstDirectiveRegex = regexp.MustCompile(`^\.\.[ \t]+([A-Za-z0-9][\w:+.-]*?)::(?:[ \t]+|$)`)
+	rstFootnoteRegex  = regexp.MustCompile(`^\.\.[ \t]+\[[^\]]+\](?:[ \t]+|$)`)
+	rstOptionRegex    = regexp.MustCompile(`^:[\w-]+:`)
+	rstBulletRegex    = regexp.MustCompile(`^[-*+•‣⁃](?:[ \t]+|$)`)
+	rstEnumRegex      = regexp.MustCompile(`^(?:\d+|#|[a-zA-Z]|[ivxlcdm]+|[IVXLCDM]+)[.)](?:[ \t]+|$)|^\((?:\d+|#|[a-zA-Z]|[ivxlcdm]+|[IVXLCDM]+)\)(?:[ \t]+|$)`)
+	rstFieldRegex     = regexp.MustCompile("^:(?:[^:\\\\`]|\\\\.)+:(?:[ \\t]+|$)")
+	rstTableRegex     = regexp.MustCompile(`^\+[-=+]+\+$|^=+(?:[ \t]+=+)+$`)
+	rstRoleRegex      = regexp.MustCompile("^:([A-Za-z][\\w:+.-]*):`")
+	rstPostRoleRegex  = regexp.MustCompile(`^:([A-Za-z][\w:+.-]*):`)
+	rstFootnoteRef    = regexp.MustCompile(`^\[(?:\d+|#[\w-]*|\*|[A-Za-z][\w.-]*)\]_`)
+	rstSubstitution   = regexp.MustCompile(`^\|[^|\s](?:[^|]*[^|\s])?\|(?:__?)?`)
+)
+
+// isAdornment reports whether a line underlines or overlines a section
+// title, or is a transition: a punctuation character repeated at least three
+// times.
+func isAdornment(text string) bool {
+	if len(text) < 3 || !isASCIIPunctuation(text[0]) {
+		return false
+	}
+	return strings.Count(text, text[:1]) == len(text)
+}
+
+// rstDirectives are the directives whose content is prose. The value tells
+// whether their arguments are prose too, like the text of a note, or not,
+// like the image of a figure. All other directives are skipped.
+var rstDirectives = map[string]bool{
+	"note": true, "warning": true, "tip": true, "important": true, "caution": true, "danger": true,
+	"attention": true, "hint": true, "error": true, "admonition": true, "seealso": true,
+	"versionadded": true, "versionchanged": true, "deprecated": true, "topic": true, "sidebar": true,
+	"rubric": true, "epigraph": true, "highlights": true, "pull-quote": true, "compound": true,
+	"glossary": false, "figure": false, "container": false, "only": false, "list-table": true,
+	"centered": true, "hlist": false,
+}
+
+// rstInlineRoles keep their text, all other roles become a placeholder.
+var rstInlineRoles = map[string]bool{
+	"emphasis": true, "strong": true, "title-reference": true, "title": true, "t": true, "abbr": true,
+	"sup": true, "sub": true, "superscript": true, "subscript": true,
+}
+
+// rstSegments extracts the prose of a reStructuredText document: section
+// titles, paragraphs, list items, field bodies and the content of
+// admonitions. Literal blocks, code and math directives, comments, targets
+// and tables are skipped.
+func rstSegments(doc *document) []segment {
+	lines := []rstLine{}
+	for i := range doc.lineStarts {
+		s := doc.lineSpan(i)
+		line := rstLine{start: s.start, end: s.end}
+		for line.start < line.end && (doc.text[line.start] == ' ' || doc.text[line.start] == '\t') {
+			if doc.text[line.start] == '\t' {
+				line.indent += 8 - line.indent%8
+			} else {
+				line.indent++
+			}
+			line.start++
+		}
+		for line.end > line.start && (doc.text[line.end-1] == ' ' || doc.text[line.end-1] == '\t') {
+			line.end--
+		}
+		lines = append(lines, line)
+	}
+
+	p := &rstParser{doc: doc}
+	p.parse(lines)
+	return p.segments
+}
+
+type rstParser struct {
+	doc      *document
+	segments []segment
+}
+
+func (p *rstParser) text(l rstLine) string {
+	return p.doc.text[l.start:l.end]
+}
+
+// indentedEnd returns the index of the first line from i on that isn't
+// blank and is indented no more than base.
+func (p *rstParser) indentedEnd(lines []rstLine, i int, base int) int {
+	for i < len(lines) && (lines[i].blank() || lines[i].indent > base) {
+		i++
+	}
+	return i
+}
+
+// body returns the lines of a list item, field or explicit markup block,
+// starting with the text after its marker on the first line. That text gets
+// the indentation of the lines after it, so that they form one paragraph.
+func (p *rstParser) body(lines []rstLine, i int, width int) ([]rstLine, int) {
+	base := lines[i].indent
+	end := p.indentedEnd(lines, i+1, base)
+	first := rstLine{start: lines[i].start + width, end: lines[i].end, indent: base + width}
+	for _, line := range lines[i+1 : end] {
+		if !line.blank() {
+			first.indent = line.indent
+			break
+		}
+	}
+	if first.start > first.end {
+		first.start = first.end
+	}
+	return append([]rstLine{first}, lines[i+1:end]...), end
+}
+
+func (p *rstParser) parse(lines []rstLine) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if line.blank() {
+			i++
+			continue
+		}
+		base := line.indent
+		text := p.text(line)
+		nextText := ""
+		if i+1 < len(lines) {
+			nextText = p.text(lines[i+1])
+		}
+
+		switch {
+		// A title between an overline and an underline
+		case isAdornment(text) && i+2 < len(lines) && nextText != "" &&
+			isAdornment(p.text(lines[i+2])) && p.text(lines[i+2])[0] == text[0]:
+			p.paragraph(lines[i+1:i+2], false)
+			i += 3
+
+		// A transition
+		case isAdornment(text) && !rstTableRegex.MatchString(text):
+			i++
+
+		// A title with an underline
+		case isAdornment(nextText) && lines[i+1].indent == base && !rstTableRegex.MatchString(nextText):
+			p.paragraph(lines[i:i+1], false)
+			i += 2
+
+		case text == ".." || strings.HasPrefix(text, ".. "):
+			i = p.explicit(lines, i)
+
+		case rstBulletRegex.MatchString(text) || rstEnumRegex.MatchString(text):
+			marker := rstBulletRegex.FindString(text)
+			if marker == "" {
+				marker = rstEnumRegex.FindString(text)
+			}
+			body, end := p.body(lines, i, len(marker))
+			p.parse(body)
+			i = end
+
+		case rstFieldRegex.MatchString(text):
+			body, end := p.body(lines, i, len(rstFieldRegex.FindString(text)))
+			p.parse(body)
+			i = end
+
+		// Line blocks keep their line breaks, so every line is on its own
+		case text == "|" || strings.HasPrefix(text, "| "):
+			for ; i < len(lines) && !lines[i].blank() && lines[i].indent == base && strings.HasPrefix(p.text(lines[i]), "|"); i++ {
+				line := lines[i]
+				line.start = min(line.start+2, line.end)
+				p.paragraph([]rstLine{line}, false)
+			}
+
+		// Doctests and tables
+		case strings.HasPrefix(text, ">>>") || rstTableRegex.MatchString(text):
+			for i < len(lines) && !lines[i].blank() {
+				i++
+			}
+
+		// A definition list item
+		case i+1 < len(lines) && !lines[i+1].blank() && lines[i+1].indent > base:
+			p.paragraph(lines[i:i+1], false)
+			end := p.indentedEnd(lines, i+1, base)
+			p.parse(lines[i+1 : end])
+			i = end
+
+		default:
+			end := i
+			for end < len(lines) && !lines[end].blank() && lines[end].indent == base {
+				end++
+			}
+			literal := strings.HasSuffix(p.text(lines[end-1]), "::")
+			p.paragraph(lines[i:end], literal)
+			i = end
+
+			// The indented block after "::" is a literal block
+			if literal {
+				next := i
+				for next < len(lines) && lines[next].blank() {
+					next++
+				}
+				if next < len(lines) && lines[next].indent > base {
+					i = p.indentedEnd(lines, next, base)
+				}
+			}
+		}
+	}
+}
+
+// explicit handles the explicit markup block starting at line i, and returns
+// the index of the line after it. Only footnotes, citations and directives
+// with prose content are checked. Comments, targets and substitution
+// definitions are skipped.
+func (p *rstParser) explicit(lines []rstLine, i int) int {
+	text := p.text(lines[i])
+
+	if match := rstDirectiveRegex.FindString(text); match != "" {
+		name := strings.ToLower(rstDirectiveRegex.FindStringSubmatch(text)[1])
+		proseArguments, prose := rstDirectives[name]
+		body, end := p.body(lines, i, len(match))
+		if !prose {
+			return end
+		}
+		if !proseArguments {
+			body[0].end = body[0].start
+		}
+
+		// Options come right after the arguments
+		j := 1
+		for j < len(body) && !body[j].blank() && rstOptionRegex.MatchString(p.text(body[j])) {
+			j++
+		}
+		p.parse(append(body[:1:1], body[j:]...))
+		return end
+	}
+
+	if match := rstFootnoteRegex.FindString(text); match != "" {
+		body, end := p.body(lines, i, len(match))
+		p.parse(body)
+		return end
+	}
+
+	return p.indentedEnd(lines, i+1, lines[i].indent)
+}
+
+// paragraph adds the text of lines, joined by spaces, as a segment. The
+// colon of a paragraph introducing a literal block stays only when it
+// follows a word, as it does in the output.
+func (p *rstParser) paragraph(lines []rstLine, literal bool) {
+	s := segment{}
+	for i, line := range lines {
+		if i > 0 {
+			s.join(" ", line.start-1)
+		}
+		s.add(p.doc, line.start, line.end)
+	}
+
+	if literal {
+		switch {
+		case strings.TrimSpace(s.text) == "::":
+			return
+		case strings.HasSuffix(s.text, " ::"):
+			s = s.slice(0, len(s.text)-3)
+		default:
+			s = s.slice(0, len(s.text)-1)
+		}
+	}
+
+	if text := rstInline(s); hasProse(text.text) {
+		p.segments = append(p.segments, text)
+	}
+}
+
+// rstInline removes reStructuredText inline markup: emphasis, hyperlink
+// references, footnote references and escapes. Literals, roles, interpreted
+// text and substitutions are replaced by a placeholder.
+func rstInline(s segment) segment {
+	result := segment{}
+	text := s.text
+
+	// Inline markup only starts after whitespace or punctuation
+	canStart := func(i int) bool {
+		if i == 0 {
+			return true
+		}
+		r, _ := utf8.DecodeLastRuneInString(text[:i])
+		return unicode.IsSpace(r) || strings.ContainsRune(`-:/'"<([{`, r)
+	}
+	canEnd := func(i int) bool {
+		if i >= len(text) {
+			return true
+		}
+		r, _ := utf8.DecodeRuneInString(text[i:])
+		return unicode.IsSpace(r) || strings.ContainsRune(`-.,:;!?\/'")]}>`, r)
+	}
+	placeholder := func(start, end int, text string) {
+		result.replace(text, s.offsets[start], s.offsets[end-1]+1)
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text):
+			_, size := utf8.DecodeRuneInString(text[i+1:])
+			if text[i+1] != ' ' {
+				result.append(s.slice(i+1, i+1+size))
+			}
+			i += 1 + size
+			continue
+
+		case strings.HasPrefix(text[i:], "``") && canStart(i):
+			if end := strings.Index(text[i+2:], "``"); end > 0 {
+				end += i + 4
+				placeholder(i, end, codePlaceholder)
+				i = end
+				continue
+			}
+
+		case c == ':' && canStart(i) && rstRoleRegex.MatchString(text[i:]):
+			match := rstRoleRegex.FindStringSubmatch(text[i:])
+			start := i + len(match[0])
+			if end := strings.IndexByte(text[start:], '`'); end > 0 {
+				end += start
+				if rstInlineRoles[match[1]] {
+					content := s.slice(start, end)
+					if match[1] == "abbr" {
+						if paren := strings.Index(content.text, " ("); paren != -1 {
+							content = content.slice(0, paren)
+						}
+					}
+					result.append(content)
+				} else {
+					placeholder(i, end+1, codePlaceholder)
+				}
+				i = end + 1
+				continue
+			}
+
+		case c == '_' && strings.HasPrefix(text[i:], "_`") && canStart(i):
+			if end := strings.IndexByte(text[i+2:], '`'); end > 0 {
+				result.append(s.slice(i+2, i+2+end))
+				i += end + 3
+				continue
+			}
+
+		case c == '`' && canStart(i):
+			end := strings.IndexByte(text[i+1:], '`')
+			if end <= 0 {
+				break
+			}
+			end += i + 1
+			content := s.slice(i+1, end)
+			after := end + 1
+
+			switch {
+			case strings.HasPrefix(text[after:], "_"):
+				// A hyperlink reference, maybe with an embedded URL
+				for after < len(text) && text[after] == '_' {
+					after++
+				}
+				if open := strings.LastIndex(content.text, "<"); open != -1 && strings.HasSuffix(content.text, ">") {
+					label := strings.TrimRight(content.text[:open], " ")
+					if label == "" {
+						placeholder(i, after, urlPlaceholder)
+						i = after
+						continue
+					}
+					content = content.slice(0, len(label))
+				}
+				result.append(content)
+			case rstPostRoleRegex.MatchString(text[after:]):
+				after += len(rstPostRoleRegex.FindString(text[after:]))
+				placeholder(i, after, codePlaceholder)
+			default:
+				placeholder(i, after, codePlaceholder)
+			}
+			i = after
+			continue
+
+		case c == '*' && canStart(i):
+			n := runLength(text, i)
+			if n > 2 || i+n >= len(text) || text[i+n] == ' ' {
+				break
+			}
+			marker := text[i : i+n]
+			end := -1
+			for j := i + n + 1; j < len(text) && end == -1; j++ {
+				if strings.HasPrefix(text[j:], marker) && text[j-1] != ' ' && canEnd(j+n) && runLength(text, j) == n {
+					end = j
+				}
+			}
+			if end != -1 {
+				result.append(s.slice(i+n, end))
+				i = end + n
+				continue
+			}
+
+		case c == '[' && rstFootnoteRef.MatchString(text[i:]):
+			i += len(rstFootnoteRef.FindString(text[i:]))
+			continue
+
+		case c == '|' && canStart(i) && rstSubstitution.MatchString(text[i:]):
+			end := i + len(rstSubstitution.FindString(text[i:]))
+			placeholder(i, end, codePlaceholder)
+			i = end
+			continue
+
+		case c == '_' && i > 0:
+			// The underscores of a reference like "Python_"
+			prev, _ := utf8.DecodeLastRuneInString(text[:i])
+			n := runLength(text, i)
+			if n <= 2 && (unicode.IsLetter(prev) || unicode.IsDigit(prev)) && canEnd(i+n) {
+				i += n
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		result.append(s.slice(i, i+size))
+		i += size
+	}
+
+	return result
+}
@@ -0,0 +1,63 @@
+package lsp
+
+import "testing"
+
+func TestRSTSegments(t *testing.T) {
+	text := "=====\nTitle\n=====\n\n" +
+		"Intro with *emphasis*, ``code`` and :ref:`target`.\n" +
+		"See `Python <https://python.org>`_ for more.\n\n" +
+		"Section\n-------\n\n" +
+		".. note:: Notes are prose.\n   They continue here.\n\n" +
+		".. code-block:: python\n   :linenos:\n\n   print(\"no\")\n\n" +
+		".. A comment.\n\n" +
+		":Author: Jane wrote this.\n\n" +
+		"- An item.\n\n" +
+		"Example::\n\n    literal text\n"
+
+	expected := []Sentence{
+		{Text: "Title", Range: Range{Position{1, 0}, Position{1, 5}}},
+		{Text: "Intro with emphasis, ⟦code⟧ and ⟦code⟧.", Range: Range{Position{4, 0}, Position{4, 50}}},
+		{Text: "See Python for more.", Range: Range{Position{5, 0}, Position{5, 44}}},
+		{Text: "Section", Range: Range{Position{7, 0}, Position{7, 7}}},
+		{Text: "Notes are prose.", Range: Range{Position{10, 10}, Position{10, 26}}},
+		{Text: "They continue here.", Range: Range{Position{11, 3}, Position{11, 22}}},
+		{Text: "Jane wrote this.", Range: Range{Position{20, 9}, Position{20, 25}}},
+		{Text: "An item.", Range: Range{Position{22, 2}, Position{22, 10}}},
+		{Text: "Example:", Range: Range{Position{24, 0}, Position{24, 8}}},
+	}
+
+	result := parseSegments(text, parseOptions{language: defaultLanguage}, rstSegments)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i := range expected {
+		testSentence(t, result[i], expected[i])
+	}
+}
+
+func TestRSTInline(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected string
+	}{
+		{"**Bold** and *em*", "Bold and em"},
+		{"Use :code:`x` here", "Use ⟦code⟧ here"},
+		{"A :emphasis:`role` stays", "A role stays"},
+		{"`Docs`_ and Python_ links", "Docs and Python links"},
+		{"`<https://example.com>`_", "⟦url⟧"},
+		{"A footnote [1]_ here", "A footnote  here"},
+		{"The |name| substitution", "The ⟦code⟧ substitution"},
+		{"An \\*escaped\\* star", "An *escaped* star"},
+		{"snake_case_name stays", "snake_case_name stays"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(test.Text)
+		s := segment{}
+		s.add(doc, 0, len(test.Text))
+		if result := rstInline(s); result.text != test.Expected {
+			t.Errorf("Expected %s, got %s", test.Expected, result.text)
+		}
+	}
+}
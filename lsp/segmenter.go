@@ -0,0 +1,229 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Segmenter splits a paragraph of prose into sentences. It returns the byte
+// ranges of the sentences, without surrounding whitespace.
+type Segmenter interface {
+	Segment(text string) []span
+}
+
+const defaultLanguage = "en"
+
+var segmenters = map[string]Segmenter{}
+
+// RegisterSegmenter makes a segmenter available for a language, given as a
+// language tag like "en" or "en-GB".
+func RegisterSegmenter(language string, segmenter Segmenter) {
+	segmenters[strings.ToLower(language)] = segmenter
+}
+
+// segmenterFor returns the segmenter of the most specific registered tag, so
+// "en-GB" falls back to "en", and English when nothing matches.
+func segmenterFor(language string) Segmenter {
+	tag := strings.ToLower(strings.ReplaceAll(language, "_", "-"))
+	for tag != "" {
+		if segmenter, ok := segmenters[tag]; ok {
+			return segmenter
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i == -1 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return segmenters[defaultLanguage]
+}
+
+func init() {
+	// Abbreviations that are words or names too, like "sat" or "Ed", only
+	// count before a number
+	RegisterSegmenter("en", NewRuleSegmenter([]string{
+		"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "mt", "vs", "etc", "e.g", "i.e", "cf", "al", "viz",
+		"approx", "dept", "inc", "ltd", "corp", "eds", "feb", "apr", "jun", "jul", "aug", "sep", "sept",
+		"oct", "nov", "dec", "tue", "thu", "fri", "a.m", "p.m", "u.s", "u.k", "ph.d", "resp", "incl", "excl",
+		"misc", "avg",
+	}, []string{
+		"no", "nos", "fig", "figs", "vol", "vols", "p", "pp", "ch", "sec", "art",
+		"jan", "mar", "mon", "wed", "sat", "sun", "min", "max", "ed", "co", "est",
+	}))
+	RegisterSegmenter("de", NewRuleSegmenter([]string{
+		"z.b", "bzw", "usw", "ca", "dr", "hr", "fr", "vgl", "d.h", "u.a", "evtl", "ggf", "inkl", "sog", "bzgl",
+		"str", "jh", "prof",
+	}, []string{"nr", "s", "abs", "abb", "bd"}))
+	RegisterSegmenter("fr", NewRuleSegmenter([]string{
+		"m", "mm", "mme", "mmes", "mlle", "dr", "pr", "p.ex", "etc", "cf", "av", "env", "ex",
+	}, []string{"p", "vol", "chap", "no"}))
+	RegisterSegmenter("es", NewRuleSegmenter([]string{
+		"sr", "sra", "srta", "sres", "dr", "dra", "ud", "uds", "etc", "p.ej", "aprox",
+	}, []string{"pág", "págs", "núm", "vol"}))
+}
+
+// RuleSegmenter splits at ".", "?", "!" and ellipses followed by whitespace,
+// except after abbreviations and initials or when the next word doesn't start
+// a sentence. Decimals, versions, file names and URLs never contain a
+// boundary because their dots aren't followed by whitespace.
+type RuleSegmenter struct {
+	abbreviations map[string]bool
+	beforeNumbers map[string]bool
+}
+
+// NewRuleSegmenter creates a segmenter that knows the given abbreviations,
+// written in lower case without their final period. Abbreviations in
+// beforeNumbers, like "no" or "fig", only count when a number follows.
+func NewRuleSegmenter(abbreviations []string, beforeNumbers []string) *RuleSegmenter {
+	s := &RuleSegmenter{abbreviations: make(map[string]bool), beforeNumbers: make(map[string]bool)}
+	for _, abbreviation := range abbreviations {
+		s.abbreviations[abbreviation] = true
+	}
+	for _, abbreviation := range beforeNumbers {
+		s.beforeNumbers[abbreviation] = true
+	}
+	return s
+}
+
+var urlRegex = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)[^\s<>"]*[^\s<>".,;:!?)\]'"]`)
+
+func isTerminal(r rune) bool {
+	return r == '.' || r == '?' || r == '!' || r == '…'
+}
+
+func isClosing(r rune) bool {
+	return strings.ContainsRune(`"')]}»”’`, r)
+}
+
+func (s *RuleSegmenter) Segment(text string) []span {
+	urls := urlRegex.FindAllStringIndex(text, -1)
+	inURL := func(i int) bool {
+		for _, url := range urls {
+			if i >= url[0] && i < url[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := []span{}
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isTerminal(r) || inURL(i) {
+			i += size
+			continue
+		}
+
+		// The sentence ends after all terminal punctuation and closing quotes
+		// and brackets.
+		end := i
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isTerminal(r) {
+				break
+			}
+			end += size
+		}
+		terminal := text[i:end]
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isClosing(r) {
+				break
+			}
+			end += size
+		}
+
+		if s.isBoundary(text, i, terminal, end) {
+			result = appendSpan(result, text, start, end)
+			start = end
+		}
+		i = end
+	}
+
+	return appendSpan(result, text, start, len(text))
+}
+
+// isBoundary decides whether the terminal punctuation at text[i:] ending
+// the candidate sentence at end really ends it.
+func (s *RuleSegmenter) isBoundary(text string, i int, terminal string, end int) bool {
+	if end == len(text) {
+		return true
+	}
+
+	next, _ := utf8.DecodeRuneInString(text[end:])
+	if !unicode.IsSpace(next) {
+		return false
+	}
+
+	rest := strings.TrimLeft(text[end:], " \t\n")
+	following, _ := utf8.DecodeRuneInString(rest)
+	if rest == "" {
+		return true
+	}
+
+	// A lower case word continues the sentence: "e.g. this", "Really?" she said
+	if unicode.IsLower(following) {
+		return false
+	}
+
+	if terminal == "." {
+		word := wordBefore(text, i)
+		lower := strings.ToLower(word)
+		if s.abbreviations[lower] || (s.beforeNumbers[lower] && unicode.IsDigit(following)) {
+			return false
+		}
+		// Initials like "J. R. R. Tolkien"
+		if r, size := utf8.DecodeRuneInString(word); size == len(word) && unicode.IsUpper(r) {
+			return false
+		}
+	}
+
+	// An ellipsis only ends a sentence when a new one clearly starts
+	if terminal == "..." || terminal == "…" {
+		return unicode.IsUpper(following) || isOpening(following)
+	}
+
+	return true
+}
+
+func isOpening(r rune) bool {
+	return strings.ContainsRune(`"'([{«“‘`, r)
+}
+
+// wordBefore returns the word ending at i, keeping inner periods so that
+// abbreviations like "e.g" are found whole.
+func wordBefore(text string, i int) string {
+	start := i
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' {
+			break
+		}
+		start -= size
+	}
+	return strings.TrimLeft(text[start:i], ".")
+}
+
+func appendSpan(spans []span, text string, start, end int) []span {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	if end > start {
+		spans = append(spans, span{start, end})
+	}
+	return spans
+}
@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		Language string
+		Text     string
+		Expected []string
+	}{
+		{"en", "Use a tool, e.g. this one. It works.", []string{"Use a tool, e.g. this one.", "It works."}},
+		{"en", "Dr. Smith arrived. He was late.", []string{"Dr. Smith arrived.", "He was late."}},
+		{"en", "Version v1.2 is out. Pi is 3.14 today.", []string{"Version v1.2 is out.", "Pi is 3.14 today."}},
+		{"en", "I read the code of Node.js Redis client.", []string{"I read the code of Node.js Redis client."}},
+		{"en", "Open main.go and see https://example.com/a.b?c=d. Then run it!", []string{"Open main.go and see https://example.com/a.b?c=d.", "Then run it!"}},
+		{"en", "He asked \"Really?\" and left. \"Yes!\" She nodded.", []string{"He asked \"Really?\" and left.", "\"Yes!\"", "She nodded."}},
+		{"en", "Wait... what happened? Well… Nothing (really.) Done.", []string{"Wait... what happened?", "Well…", "Nothing (really.)", "Done."}},
+		{"en", "See fig. 3 for details. I said no. Then I left.", []string{"See fig. 3 for details.", "I said no.", "Then I left."}},
+		{"en", "J. R. R. Tolkien wrote it.", []string{"J. R. R. Tolkien wrote it."}},
+		{"en", "We sat in the sun. Then it rained.", []string{"We sat in the sun.", "Then it rained."}},
+		{"en", "Set it to max. Then wait.", []string{"Set it to max.", "Then wait."}},
+		{"en", "I met Ed. He waved.", []string{"I met Ed.", "He waved."}},
+		{"en", "It opens on Mar. 3 at 9 a.m. and takes 5 min. 30 s at most.", []string{"It opens on Mar. 3 at 9 a.m. and takes 5 min. 30 s at most."}},
+		{"de-AT", "Das ist z.B. gut. Siehe S. 4 dazu.", []string{"Das ist z.B. gut.", "Siehe S. 4 dazu."}},
+	}
+
+	for _, test := range tests {
+		spans := segmenterFor(test.Language).Segment(test.Text)
+		result := []string{}
+		for _, s := range spans {
+			result = append(result, test.Text[s.start:s.end])
+		}
+
+		if len(result) != len(test.Expected) {
+			t.Errorf("Expected %q, got %q", test.Expected, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != test.Expected[i] {
+				t.Errorf("Expected %q, got %q", test.Expected[i], result[i])
+			}
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,7 +22,26 @@ import (
 )
 
 type ModelConfig struct {
-	Key string `json:"key"`
+	Key         string           `json:"key"`
+	Diagnostics DiagnosticConfig `json:"diagnostics"`
+	Dictionary  DictionaryConfig `json:"dictionary"`
+	// Language is the language tag of the documents, e.g. "en-GB"
+	Language string `json:"language"`
+	// CheckComments enables checking the comments of notebook code cells.
+	CheckComments bool `json:"checkComments"`
+	// CheckOnType checks documents while typing, Debounce milliseconds after
+	// the last change.
+	CheckOnType bool `json:"checkOnType"`
+	Debounce    int  `json:"debounce"`
+	// Exclusions adjusts the recognizers of math, shortcodes and other
+	// markup that isn't checked.
+	Exclusions ExclusionConfig `json:"exclusions"`
+	// MessageFuncs are the Go functions whose string arguments are checked,
+	// like "errors.New" or "fmt.Errorf".
+	MessageFuncs []string `json:"messageFuncs"`
+	// CaptionLineLength is the longest line of a subtitle cue, 42 characters
+	// when unset.
+	CaptionLineLength int `json:"captionLineLength"`
 }
 
 func readConfig() (ModelConfig, error) {
@@ -56,6 +76,13 @@ type Server struct {
 	ModelConfig ModelConfig
 	db          *sql.DB
 	limiter     *rate.Limiter
+	dictionary  *Dictionary
+	notebooks   map[string]*notebook
+	cellKinds   map[string]int
+	languageIDs map[string]string
+	pending     map[string]*pendingCheck
+	pendingMu   sync.Mutex
+	exclusions  []Exclusion
 	mu          sync.Mutex
 }
 
@@ -95,20 +122,100 @@ func NewServer() *Server {
 		ModelConfig: config,
 		db:          db,
 		limiter:     limiter,
+		dictionary:  NewDictionary(config.Dictionary),
+		notebooks:   make(map[string]*notebook),
+		cellKinds:   make(map[string]int),
+		languageIDs: make(map[string]string),
+		pending:     make(map[string]*pendingCheck),
+		exclusions:  config.Exclusions.Exclusions(logger),
 	}
 }
 
+// projectDictionary is a file in the workspace root listing project terms,
+// one per line.
+const projectDictionary = ".jalsa-dictionary"
+
+// OpenWorkspace loads the project dictionary of the workspace at rootURI.
+func (s *Server) OpenWorkspace(rootURI string) {
+	if rootURI == "" {
+		return
+	}
+
+	path := filepath.Join(strings.TrimPrefix(rootURI, "file://"), projectDictionary)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	s.dictionary.AddWords(strings.Fields(string(data)))
+}
+
+// OpenDocument stores a text document and remembers its language, which
+// picks the parser.
+func (s *Server) OpenDocument(item TextDocumentItem) {
+	s.Files[item.URI] = item.Text
+	s.languageIDs[item.URI] = item.LanguageID
+}
+
+// parse extracts the sentences of a file. Notebook code cells only have their
+// comments checked, and documents in a language with a parser of its own
+// don't go through the markdown parser.
+func (s *Server) parse(fileURI string, text string) []Sentence {
+	if s.cellKinds[fileURI] == NotebookCellKindCode {
+		return s.parseComments(fileURI, text)
+	}
+	if locale := s.locale(fileURI); locale != "" {
+		return parseSegments(text, s.parseOptions(), localeSegments(s.languageIDs[fileURI], locale))
+	}
+	if extract, ok := documentParsers[s.languageIDs[fileURI]]; ok {
+		return parseSegments(text, s.parseOptions(), extract)
+	}
+	return parseDocument(text, s.parseOptions())
+}
+
+func (s *Server) parseOptions() parseOptions {
+	return parseOptions{language: s.ModelConfig.Language, exclusions: s.exclusions, messageFuncs: s.ModelConfig.MessageFuncs}
+}
+
+// locale returns the locale of a JSON or YAML file in a locale directory, or
+// "" for other files.
+func (s *Server) locale(fileURI string) string {
+	switch s.languageIDs[fileURI] {
+	case "json", "jsonc", "yaml":
+		return localeOf(fileURI)
+	}
+	return ""
+}
+
+// isMarkdown reports whether a file is parsed as markdown.
+func (s *Server) isMarkdown(fileURI string) bool {
+	_, ok := documentParsers[s.languageIDs[fileURI]]
+	return !ok && s.locale(fileURI) == "" && s.cellKinds[fileURI] != NotebookCellKindCode
+}
+
+// lint runs the checks that don't need the model.
+func (s *Server) lint(fileURI string, text string) []Diagnostic {
+	switch {
+	case s.languageIDs[fileURI] == "gitcommit":
+		return commitDiagnostics(s.ModelConfig.Diagnostics, text)
+	case contains(captionLanguageIDs, s.languageIDs[fileURI]):
+		return captionDiagnostics(s.ModelConfig.Diagnostics, s.ModelConfig.CaptionLineLength, text)
+	case !s.isMarkdown(fileURI):
+		return []Diagnostic{}
+	}
+	return accessibilityDiagnostics(s.ModelConfig.Diagnostics, text, s.parseOptions())
+}
+
 func (s *Server) CachedDiagnostics(fileURI string) *PublishDiagnosticsNotification {
 	text := s.Files[fileURI]
 
-	sentences := parse(text)
-	diagnostics := []Diagnostic{}
+	sentences := s.parse(fileURI, text)
+	diagnostics := s.lint(fileURI, text)
 
 	for _, sentence := range sentences {
 		check, cached := s.cachedCheck(sentence)
 		if cached {
 			if check.HasError {
-				diagnostics = append(diagnostics, ConvertCheckToDiagnostic(*check))
+				diagnostics = append(diagnostics, ConvertCheckToDiagnostics(s.ModelConfig.Diagnostics, fileURI, sentence, *check)...)
 			}
 		}
 	}
@@ -119,9 +226,35 @@ func (s *Server) CachedDiagnostics(fileURI string) *PublishDiagnosticsNotificati
 func (s *Server) Analyze(fileURI string) *PublishDiagnosticsNotification {
 	text := s.Files[fileURI]
 
-	sentences := parse(text)
-	diagnostics := []Diagnostic{}
+	notification, _ := s.analyze(context.Background(), fileURI, s.lint(fileURI, text), s.parse(fileURI, text))
+	return notification
+}
+
+// Check is Analyze for the command line: it also returns an error when some
+// sentences couldn't be checked, like when the model can't be reached.
+func (s *Server) Check(fileURI string) (*PublishDiagnosticsNotification, error) {
+	text := s.Files[fileURI]
+
+	return s.analyze(context.Background(), fileURI, s.lint(fileURI, text), s.parse(fileURI, text))
+}
+
+// analyze checks sentences and adds the result to diagnostics, those of the
+// checks that don't need the model. Sentences whose hash is already in the
+// cache are not sent to the model again, so after an edit only the sentences
+// that changed are checked. Sentences that couldn't be checked are logged
+// and counted in the error.
+func (s *Server) analyze(ctx context.Context, fileURI string, diagnostics []Diagnostic, sentences []Sentence) (*PublishDiagnosticsNotification, error) {
 	var wg sync.WaitGroup
+	failed := 0
+	var failure error
+	fail := func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		failed++
+		if failure == nil {
+			failure = err
+		}
+	}
 
 	// TODO: parallelize requests to check sentences
 	for _, sentence := range sentences {
@@ -133,33 +266,48 @@ func (s *Server) Analyze(fileURI string) *PublishDiagnosticsNotification {
 				if check.HasError {
 					s.mu.Lock()
 					defer s.mu.Unlock()
-					diagnostics = append(diagnostics, ConvertCheckToDiagnostic(*check))
+					diagnostics = append(diagnostics, ConvertCheckToDiagnostics(s.ModelConfig.Diagnostics, fileURI, sentence, *check)...)
 				}
 				return
 			}
 
-			err := s.limiter.Wait(context.Background())
+			err := s.limiter.Wait(ctx)
+			if ctx.Err() != nil {
+				return
+			}
 			if err != nil {
 				s.Logger.Println("Rate Limit Error: ", err)
+				fail(err)
 				return
 			}
 
-			check, err = s.checkSentence(sentence)
+			check, err = s.checkSentence(ctx, sentence)
 			if err != nil {
-				s.Logger.Println("Error checking sentence: ", err)
+				if ctx.Err() == nil {
+					s.Logger.Println("Error checking sentence: ", err)
+					fail(err)
+				}
+				return
+			}
+			// A check that completed is cached even when a newer edit cancelled
+			// it, so that it isn't paid for again
+			s.saveCheck(sentence, *check)
+			if ctx.Err() != nil {
 				return
 			}
 			if check.HasError {
 				s.mu.Lock()
 				defer s.mu.Unlock()
-				diagnostics = append(diagnostics, ConvertCheckToDiagnostic(*check))
+				diagnostics = append(diagnostics, ConvertCheckToDiagnostics(s.ModelConfig.Diagnostics, fileURI, sentence, *check)...)
 			}
-			s.saveCheck(sentence.Text, *check)
 		}(sentence)
 	}
 	wg.Wait()
 
-	return NewDiagnostics(fileURI, diagnostics)
+	if failed > 0 {
+		return NewDiagnostics(fileURI, diagnostics), fmt.Errorf("%d of %d sentences weren't checked: %w", failed, len(sentences), failure)
+	}
+	return NewDiagnostics(fileURI, diagnostics), nil
 }
 
 type SentenceCheck struct {
@@ -167,12 +315,14 @@ type SentenceCheck struct {
 	HasError    bool   `json:"hasError"`
 	Correction  string `json:"correction"`
 	Explanation string `json:"explanation"`
+	Category    string `json:"category"`
+	Rule        string `json:"rule" description:"Short kebab-case id of the rule that was broken, e.g. subject-verb-agreement"`
 }
 
 func (s *Server) cachedCheck(sentence Sentence) (*SentenceCheck, bool) {
 	var result string
 	sentenceCheck := new(SentenceCheck)
-	err := s.db.QueryRow("SELECT correction FROM sentences WHERE sentence_hash = ?", hash(sentence.Text)).Scan(&result)
+	err := s.db.QueryRow("SELECT correction FROM sentences WHERE sentence_hash = ?", sentence.hash()).Scan(&result)
 
 	if err != nil && err != sql.ErrNoRows {
 		s.Logger.Println("Database Read Error: ", err)
@@ -190,25 +340,31 @@ func (s *Server) cachedCheck(sentence Sentence) (*SentenceCheck, bool) {
 	}
 
 	sentenceCheck.Range = sentence.Range
+	if sentenceCheck.Category == "" {
+		sentenceCheck.Category = CategoryGrammar
+	}
 
 	return sentenceCheck, true
 }
 
-func (s *Server) saveCheck(sentence string, sentenceCheck SentenceCheck) {
+func (s *Server) saveCheck(sentence Sentence, sentenceCheck SentenceCheck) {
 	data, err := json.Marshal(sentenceCheck)
 	if err != nil {
 		s.Logger.Println("Error marshalling: ", err)
 		return
 	}
-	_, err = s.db.Exec("INSERT INTO sentences (sentence_hash, sentence, correction) VALUES (?, ?, ?)", hash(sentence), sentence, string(data))
+	_, err = s.db.Exec("INSERT INTO sentences (sentence_hash, sentence, correction) VALUES (?, ?, ?)", sentence.hash(), sentence.Text, string(data))
 	if err != nil {
 		s.Logger.Println("Error saving: ", err)
 		return
 	}
 }
 
-func (s *Server) checkSentence(sentence Sentence) (*SentenceCheck, error) {
+func (s *Server) checkSentence(ctx context.Context, sentence Sentence) (*SentenceCheck, error) {
 	prompt := "Check this sentence\n----\n%s"
+	if sentence.language != "" {
+		prompt = "Check this sentence, written in " + sentence.language + "\n----\n%s"
+	}
 	prompt = fmt.Sprintf(prompt, sentence.Text)
 
 	client := openai.NewClient(s.ModelConfig.Key)
@@ -217,6 +373,9 @@ func (s *Server) checkSentence(sentence Sentence) (*SentenceCheck, error) {
 	if err != nil {
 		log.Fatalf("GenerateSchemaForType error: %v", err)
 	}
+	category := schema.Properties["category"]
+	category.Enum = Categories
+	schema.Properties["category"] = category
 
 	responseFormat := &openai.ChatCompletionResponseFormat{
 		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
@@ -228,7 +387,7 @@ func (s *Server) checkSentence(sentence Sentence) (*SentenceCheck, error) {
 	}
 
 	resp, err := client.CreateChatCompletion(
-		context.Background(),
+		ctx,
 		openai.ChatCompletionRequest{
 			Model: openai.GPT4o20240806,
 			Messages: []openai.ChatCompletionMessage{
@@ -236,13 +395,15 @@ func (s *Server) checkSentence(sentence Sentence) (*SentenceCheck, error) {
 					Role: openai.ChatMessageRoleSystem,
 					Content: `**System Prompt: Grammatical Error Detection and Correction**
 
-Ignore any markdown formatting, such as bold, italics, etc. and only focus on the original sentence.
+Markdown formatting has already been removed from the sentence. "⟦code⟧" and "⟦url⟧" are placeholders for inline code and links: keep them exactly as they are in the corrected sentence and never count them as mistakes.
 
 1. **Input:** Provide a sentence with potential grammatical errors.
 
 2. **Output:**
    - **Corrected Sentence:** Present the sentence in its correct grammatical form.
    - **Explanation:** Concisely describe the grammatical mistakes in the original sentence and the corrections made.
+   - **Category:** Classify the most important mistake as one of "grammar", "spelling", "punctuation", "style" or "clarity".
+   - **Rule:** Name the rule that was broken with a short kebab-case id, e.g. "subject-verb-agreement", "verb-tense", "comma-splice", "wordiness".
 
 **Example:**
 
@@ -252,7 +413,11 @@ Ignore any markdown formatting, such as bold, italics, etc. and only focus on th
 
 - **Explanation:** The verb "go" is incorrectly used in the present tense instead of the past tense. Corrected to "went" to match the past tense context indicated by "yesterday."
 
-If the sentence is grammatical correct, only reply with "{ "hasError": false, "Correction": "", "Explanation": "" }".
+- **Category:** "grammar"
+
+- **Rule:** "verb-tense"
+
+If the sentence is grammatical correct, only reply with "{ "hasError": false, "correction": "", "explanation": "", "category": "grammar", "rule": "" }".
 `,
 				},
 				{
@@ -278,6 +443,15 @@ If the sentence is grammatical correct, only reply with "{ "hasError": false, "C
 	return result, nil
 }
 
+// hash is the cache key of a sentence. Sentences in another language than
+// the default are cached apart from the same text in the default language.
+func (s Sentence) hash() string {
+	if s.language == "" {
+		return hash(s.Text)
+	}
+	return hash(s.language + "\n" + s.Text)
+}
+
 func hash(s string) string {
 	h := sha256.New()
 	h.Write([]byte(s))
@@ -0,0 +1,166 @@
+package lsp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// captionLanguageIDs are the language ids of SubRip and WebVTT subtitles.
+var captionLanguageIDs = []string{"srt", "subrip", "vtt", "webvtt"}
+
+func init() {
+	for _, id := range captionLanguageIDs {
+		registerParser(id, captionSegments)
+	}
+}
+
+// CategoryCaption is reported by the checks of subtitle lines, not by the
+// model.
+const CategoryCaption = "caption"
+
+// captionLineLength is the longest line of a cue when the config doesn't set
+// one, the limit of most broadcast guidelines.
+const captionLineLength = 42
+
+var (
+	captionTagRegex    = regexp.MustCompile(`^(?:<[^<>\n]*>|\{\\[^{}\n]*\})`)
+	captionDialogRegex = regexp.MustCompile(`^-[ \t]*`)
+	captionSkipRegex   = regexp.MustCompile(`^(?:WEBVTT|NOTE|STYLE|REGION)(?:[ \t]|$)`)
+	captionEndRegex    = regexp.MustCompile(`[.!?…♪]["'”’)\]]*$`)
+)
+
+// captionEntities are the character references of cue text.
+var captionEntities = map[string]string{
+	"&amp;": "&", "&lt;": "<", "&gt;": ">", "&nbsp;": " ", "&lrm;": "", "&rlm;": "",
+}
+
+// parseCues returns the text lines of every cue of a SubRip or WebVTT file.
+// Indices, cue identifiers and timing lines are dropped, as are the header,
+// NOTE, STYLE and REGION blocks of WebVTT.
+func parseCues(doc *document) [][]span {
+	cues := [][]span{}
+	block := []span{}
+	skip := false
+	flush := func() {
+		for i, line := range block {
+			if strings.Contains(doc.text[line.start:line.end], "-->") {
+				if i+1 < len(block) {
+					cues = append(cues, block[i+1:])
+				}
+				break
+			}
+		}
+		block = []span{}
+		skip = false
+	}
+
+	for i := range doc.lineStarts {
+		line := doc.lineSpan(i)
+		text := strings.TrimRight(doc.text[line.start:line.end], " \t\r")
+		line.end = line.start + len(text)
+		switch {
+		case text == "":
+			flush()
+		case len(block) == 0 && !skip && captionSkipRegex.MatchString(text):
+			// A block that isn't a cue, up to the next blank line
+			skip = true
+		case !skip:
+			block = append(block, line)
+		}
+	}
+	flush()
+
+	return cues
+}
+
+// captionLine returns the visible text of a cue line, without tags like <i>
+// or {\an8} and the dash of a dialog line, with character references
+// decoded.
+func captionLine(doc *document, line span) segment {
+	s := segment{}
+	start := line.start + len(captionDialogRegex.FindString(doc.text[line.start:line.end]))
+	for i := start; i < line.end; {
+		text := doc.text[i:line.end]
+		if tag := captionTagRegex.FindString(text); tag != "" {
+			i += len(tag)
+			continue
+		}
+		if text[0] == '&' {
+			if end := strings.IndexByte(text, ';'); end != -1 {
+				if value, ok := captionEntities[text[:end+1]]; ok {
+					if value != "" {
+						s.replace(value, i, i+end+1)
+					}
+					i += end + 1
+					continue
+				}
+			}
+		}
+		s.add(doc, i, i+1)
+		i++
+	}
+	return s
+}
+
+// captionSegments extracts the text of subtitle cues. A cue that doesn't end
+// a sentence is joined with the next one, so that sentences spanning cues are
+// checked whole.
+func captionSegments(doc *document) []segment {
+	segments := []segment{}
+	current := segment{}
+	for _, cue := range parseCues(doc) {
+		for _, line := range cue {
+			text := captionLine(doc, line)
+			if len(text.text) == 0 {
+				continue
+			}
+			if len(current.text) > 0 {
+				current.join(" ", current.offsets[len(current.offsets)-1]+1)
+			}
+			current.append(text)
+		}
+		if captionEndRegex.MatchString(current.text) {
+			if hasProse(current.text) {
+				segments = append(segments, current)
+			}
+			current = segment{}
+		}
+	}
+	if hasProse(current.text) {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+// captionDiagnostics reports the cue lines longer than limit characters, or
+// captionLineLength when limit is 0.
+func captionDiagnostics(config DiagnosticConfig, limit int, text string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	if limit <= 0 {
+		limit = captionLineLength
+	}
+
+	doc := newDocument(text)
+	for _, cue := range parseCues(doc) {
+		for _, line := range cue {
+			s := captionLine(doc, line)
+			length := utf8.RuneCountInString(s.text)
+			if length <= limit {
+				continue
+			}
+			cut := len(string([]rune(s.text)[:limit]))
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:           Range{Start: doc.position(s.offsets[cut]), End: doc.position(line.end)},
+				Severity:        config.severity(CategoryCaption),
+				Code:            CategoryCaption + "/line-length",
+				CodeDescription: config.codeDescription(CategoryCaption),
+				Source:          "jalsa",
+				Message:         "Line is " + strconv.Itoa(length) + " characters long. Keep it to " + strconv.Itoa(limit) + ".",
+			})
+		}
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,59 @@
+package lsp
+
+import "testing"
+
+const testSubRip = "1\n00:00:01,000 --> 00:00:03,000\n<i>We was going</i> to the\n\n2\n00:00:03,500 --> 00:00:05,000\nstore yesterday.\n- Did you buy milk?\n\n3\n00:00:06,000 --> 00:00:08,000\n{\\an8}This line is definitely far too long for a caption &amp; more.\n"
+
+const testWebVTT = "WEBVTT\n\nNOTE This is a note.\n\nintro\n00:01.000 --> 00:03.000 align:start\n<v Anna>Their is a problem\n\n00:03.000 --> 00:05.000\nwith the engine.\n"
+
+func TestCaptionSegments(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Expected []Sentence
+	}{
+		{testSubRip, []Sentence{
+			{Text: "We was going to the store yesterday.", Range: Range{Position{2, 3}, Position{6, 16}}},
+			{Text: "Did you buy milk?", Range: Range{Position{7, 2}, Position{7, 19}}},
+			{Text: "This line is definitely far too long for a caption & more.", Range: Range{Position{11, 6}, Position{11, 68}}},
+		}},
+		{testWebVTT, []Sentence{
+			{Text: "Their is a problem with the engine.", Range: Range{Position{6, 8}, Position{9, 16}}},
+		}},
+	}
+
+	for _, test := range tests {
+		result := parseSegments(test.Text, parseOptions{language: defaultLanguage}, captionSegments)
+		if len(result) != len(test.Expected) {
+			t.Errorf("Expected %d sentences, got %d: %v", len(test.Expected), len(result), result)
+			continue
+		}
+		for i := range test.Expected {
+			testSentence(t, result[i], test.Expected[i])
+		}
+	}
+}
+
+func TestCaptionDiagnostics(t *testing.T) {
+	tests := []struct {
+		Text     string
+		Limit    int
+		Expected []Range
+	}{
+		{testSubRip, 0, []Range{{Position{11, 48}, Position{11, 68}}}},
+		{testSubRip, 18, []Range{{Position{2, 25}, Position{2, 26}}, {Position{11, 24}, Position{11, 68}}}},
+		{testWebVTT, 0, nil},
+	}
+
+	for _, test := range tests {
+		diagnostics := captionDiagnostics(DiagnosticConfig{}, test.Limit, test.Text)
+		if len(diagnostics) != len(test.Expected) {
+			t.Errorf("Expected %d diagnostics, got %v", len(test.Expected), diagnostics)
+			continue
+		}
+		for i, expected := range test.Expected {
+			if got := diagnostics[i]; got.Range != expected || got.Code != "caption/line-length" {
+				t.Errorf("Expected caption/line-length at %v, got %s at %v", expected, got.Code, got.Range)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	text := `Parameters of the call:
+| Name | Default | Description |
+| ---- | ------: | :---------- |
+| ` + "`timeout`" + ` | 30 | How long to waits. In seconds. |
+| retries | 3.5% | Number of retry \| attempts |
+
+> Key | Value
+> --- | ---
+> a | 1
+
+Not | a table
+`
+	expected := []Sentence{
+		{Text: "Parameters of the call:", Range: Range{Position{0, 0}, Position{0, 23}}},
+		{Text: "Name", Range: Range{Position{1, 2}, Position{1, 6}}},
+		{Text: "Default", Range: Range{Position{1, 9}, Position{1, 16}}},
+		{Text: "Description", Range: Range{Position{1, 19}, Position{1, 30}}},
+		{Text: "How long to waits.", Range: Range{Position{3, 19}, Position{3, 37}}},
+		{Text: "In seconds.", Range: Range{Position{3, 38}, Position{3, 49}}},
+		{Text: "retries", Range: Range{Position{4, 2}, Position{4, 9}}},
+		{Text: "Number of retry | attempts", Range: Range{Position{4, 19}, Position{4, 46}}},
+		{Text: "Key", Range: Range{Position{6, 2}, Position{6, 5}}},
+		{Text: "Value", Range: Range{Position{6, 8}, Position{6, 13}}},
+		{Text: "a", Range: Range{Position{8, 2}, Position{8, 3}}},
+		{Text: "Not | a table", Range: Range{Position{10, 0}, Position{10, 13}}},
+	}
+
+	result := parse(text)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d sentences, got %d: %v", len(expected), len(result), result)
+	}
+	for i, sentence := range expected {
+		testSentence(t, result[i], sentence)
+	}
+}
@@ -3,14 +3,27 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"jalsa/lsp"
 	"jalsa/rpc"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		if len(os.Args) == 2 {
+			fmt.Fprintln(os.Stderr, "usage: jalsa check FILE.docx|FILE.odt...")
+			os.Exit(2)
+		}
+		os.Exit(check(os.Args[2:]))
+	}
+
 	scanner := bufio.NewScanner(os.Stdin)
 	scanner.Split(rpc.Split)
 
@@ -39,6 +52,7 @@ func handleMessage(msg []byte, writer io.Writer, method string, server *lsp.Serv
 			return
 		}
 		server.Logger.Printf("Connected to client %s %s", request.Params.ClientInfo.Name, request.Params.ClientInfo.Version)
+		server.OpenWorkspace(request.Params.RootURI)
 
 		response := lsp.NewInitializeResponse(request.ID)
 		writeMessage(writer, response)
@@ -49,7 +63,7 @@ func handleMessage(msg []byte, writer io.Writer, method string, server *lsp.Serv
 			return
 		}
 
-		server.Files[notification.Params.TextDocument.URI] = notification.Params.TextDocument.Text
+		server.OpenDocument(notification.Params.TextDocument)
 
 		diagnosticsNotification := server.Analyze(notification.Params.TextDocument.URI)
 		writeMessage(writer, diagnosticsNotification)
@@ -61,7 +75,15 @@ func handleMessage(msg []byte, writer io.Writer, method string, server *lsp.Serv
 			return
 		}
 
-		server.Files[notification.Params.TextDocument.URI] = notification.Params.ContentChanges[0].Text
+		fileURI := notification.Params.TextDocument.URI
+		server.Files[fileURI] = notification.Params.ContentChanges[0].Text
+
+		if server.ModelConfig.CheckOnType {
+			writeMessage(writer, server.CachedDiagnostics(fileURI))
+			server.ScheduleAnalyze(fileURI, func(diagnosticsNotification *lsp.PublishDiagnosticsNotification) {
+				writeMessage(writer, diagnosticsNotification)
+			})
+		}
 	case "textDocument/didSave":
 		notification := new(lsp.DidSaveTextDocumentNotification)
 		if err := json.Unmarshal(msg, notification); err != nil {
@@ -69,17 +91,141 @@ func handleMessage(msg []byte, writer io.Writer, method string, server *lsp.Serv
 			return
 		}
 
+		server.CancelPending(notification.Params.TextDocument.URI)
 		writeMessage(writer, server.CachedDiagnostics(notification.Params.TextDocument.URI))
 		diagnosticsNotification := server.Analyze(notification.Params.TextDocument.URI)
 		writeMessage(writer, diagnosticsNotification)
 
+	case "notebookDocument/didOpen":
+		notification := new(lsp.DidOpenNotebookDocumentNotification)
+		if err := json.Unmarshal(msg, notification); err != nil {
+			server.Logger.Printf("We could not parse %s %s", method, err)
+			return
+		}
+
+		server.OpenNotebook(notification.Params)
+		for _, cellURI := range server.NotebookCells(notification.Params.NotebookDocument.URI) {
+			writeMessage(writer, server.Analyze(cellURI))
+		}
+
+	case "notebookDocument/didChange":
+		notification := new(lsp.DidChangeNotebookDocumentNotification)
+		if err := json.Unmarshal(msg, notification); err != nil {
+			server.Logger.Printf("We could not parse %s %s", method, err)
+			return
+		}
+
+		cellURIs, closed := server.ChangeNotebook(notification.Params)
+		for _, diagnosticsNotification := range closed {
+			writeMessage(writer, diagnosticsNotification)
+		}
+		if server.ModelConfig.CheckOnType {
+			for _, cellURI := range cellURIs {
+				writeMessage(writer, server.CachedDiagnostics(cellURI))
+				server.ScheduleAnalyze(cellURI, func(diagnosticsNotification *lsp.PublishDiagnosticsNotification) {
+					writeMessage(writer, diagnosticsNotification)
+				})
+			}
+		}
+
+	case "notebookDocument/didSave":
+		notification := new(lsp.DidSaveNotebookDocumentNotification)
+		if err := json.Unmarshal(msg, notification); err != nil {
+			server.Logger.Printf("We could not parse %s %s", method, err)
+			return
+		}
+
+		cellURIs := server.NotebookCells(notification.Params.NotebookDocument.URI)
+		for _, cellURI := range cellURIs {
+			server.CancelPending(cellURI)
+			writeMessage(writer, server.CachedDiagnostics(cellURI))
+		}
+		for _, cellURI := range cellURIs {
+			writeMessage(writer, server.Analyze(cellURI))
+		}
+
+	case "notebookDocument/didClose":
+		notification := new(lsp.DidCloseNotebookDocumentNotification)
+		if err := json.Unmarshal(msg, notification); err != nil {
+			server.Logger.Printf("We could not parse %s %s", method, err)
+			return
+		}
+
+		for _, diagnosticsNotification := range server.CloseNotebook(notification.Params) {
+			writeMessage(writer, diagnosticsNotification)
+		}
+
+	case "textDocument/codeAction":
+		request := new(lsp.CodeActionRequest)
+		if err := json.Unmarshal(msg, request); err != nil {
+			server.Logger.Printf("We could not parse %s %s", method, err)
+			return
+		}
+
+		writeMessage(writer, server.CodeAction(request))
+
+	case "textDocument/completion":
+		request := new(lsp.CompletionRequest)
+		if err := json.Unmarshal(msg, request); err != nil {
+			server.Logger.Printf("We could not parse %s %s", method, err)
+			return
+		}
+
+		writeMessage(writer, server.Completion(request))
+
 	default:
 		server.Logger.Println(string(msg))
 	}
 
 }
 
+// check prints the diagnostics of .docx and .odt files by paragraph number,
+// for documents that aren't edited in an editor. It returns 1 when a file
+// has diagnostics, can't be read or some of its sentences can't be checked.
+func check(paths []string) int {
+	server := lsp.NewServer()
+	status := 0
+
+	for _, path := range paths {
+		paragraphs, err := lsp.ReadOfficeDocument(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+			continue
+		}
+
+		absolute, _ := filepath.Abs(path)
+		fileURI := "file://" + absolute
+		server.OpenDocument(lsp.TextDocumentItem{URI: fileURI, LanguageID: lsp.OfficeLanguageID, Text: strings.Join(paragraphs, "\n")})
+
+		notification, err := server.Check(fileURI)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+		}
+		diagnostics := notification.Params.Diagnostics
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			a, b := diagnostics[i].Range.Start, diagnostics[j].Range.Start
+			return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+		})
+		for _, diagnostic := range diagnostics {
+			fmt.Printf("%s: paragraph %d: %s: %s\n", path, diagnostic.Range.Start.Line+1, diagnostic.Code,
+				strings.Join(strings.Fields(diagnostic.Message), " "))
+			status = 1
+		}
+	}
+
+	return status
+}
+
+// writeMu serializes writes, as debounced checks publish from their own
+// goroutines.
+var writeMu sync.Mutex
+
 func writeMessage(writer io.Writer, message any) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
 	data := rpc.EncodeMessage(message)
 	writer.Write([]byte(data))
 }